@@ -3,33 +3,89 @@ package cmd
 import (
 	"flag"
 	"fmt"
+	"log"
 	"os"
 
 	"github.com/tornermarton/timesheets/internal/cli"
 	cfg "github.com/tornermarton/timesheets/internal/config"
+	"github.com/tornermarton/timesheets/internal/constants"
+	"github.com/tornermarton/timesheets/internal/utils"
 )
 
-func config(context *cli.Context) {
-	cfg.Print(context.Config)
+func config(context *cli.Context, showSecrets bool, resolved bool) {
+	config_ := context.Config
+	if resolved {
+		var err error
+		if config_, err = config_.Resolve(); err != nil {
+			log.Fatalf("error resolving config: %s\n", utils.GetErrorMessage(err))
+		}
+	}
+
+	if err := cfg.Print(config_, showSecrets); err != nil {
+		log.Fatalf("error printing config: %s\n", utils.GetErrorMessage(err))
+	}
 }
 
 func Config(args []string, context *cli.Context) {
-	command := &cli.FlagSet{FlagSet: flag.NewFlagSet("config", flag.ExitOnError)}
+	command := &cli.FlagSet{FlagSet: flag.NewFlagSet("config", flag.ContinueOnError)}
+
+	showSecretsFlag := command.Bool("show-secrets", false, "print secrets (tokens, passwords and API keys) instead of redacting them")
+	resolvedFlag := command.Bool("resolved", false, "print the effective configuration, with every default applied")
 
 	command.Usage = func() {
-		fmt.Printf(`Usage: timesheets config
+		fmt.Printf(`Usage: timesheets config [options] [command]
+
+Print the used configuration, with secrets (tokens, passwords and API keys)
+redacted, so it can be shared safely.
+
+Values of the configuration may reference environment variables as ${NAME}.
+Instead of a plain token, specs may give a token_file (read from a secret
+file, relative to the configuration) or a token_command (the output of a shell
+command, e.g. a password manager):
+
+  source:
+    kind: TogglTrack
+    spec:
+      workspace: ${TOGGL_WORKSPACE}
+      token: ${TOGGL_TOKEN}
+
+  target:
+    kind: CapsysKronos
+    spec:
+      token_command: pass show capsys/kronos
 
-Print the used configuration.
+Commands:
+
+  init      Create the configuration interactively.
+  schema    Print the JSON Schema of the configuration file.
+  validate  Validate the configuration, reporting every problem.
+
+Options:
+
+`)
+		command.PrintDefaults()
+		fmt.Printf(`
+Example (print the effective configuration, including defaults):
+
+  timesheets config --resolved
 
 For more information, visit: https://github.com/tornermarton/timesheets
 `)
 	}
 
 	command.Parse(args)
-	if command.NArg() > 0 {
+
+	switch command.Arg(0) {
+	case "":
+		config(context, *showSecretsFlag, *resolvedFlag)
+	case "init":
+		ConfigInit(command.Args()[1:], context)
+	case "schema":
+		ConfigSchema(command.Args()[1:], context)
+	case "validate":
+		ConfigValidate(command.Args()[1:], context)
+	default:
 		command.Usage()
-		os.Exit(1)
+		os.Exit(constants.EXIT_ERROR)
 	}
-
-	config(context)
 }
@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/term"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tornermarton/timesheets/internal/cli"
+	cfg "github.com/tornermarton/timesheets/internal/config"
+	"github.com/tornermarton/timesheets/internal/constants"
+	"github.com/tornermarton/timesheets/internal/entries"
+	"github.com/tornermarton/timesheets/internal/utils"
+)
+
+// prompter asks the questions of config init on the terminal.
+type prompter struct {
+	reader *bufio.Reader
+}
+
+func (p *prompter) line() string {
+	line, err := p.reader.ReadString('\n')
+	if err != nil && line == "" {
+		log.Fatalf("error reading answer: %s\n", utils.GetErrorMessage(err))
+	}
+	return strings.TrimSpace(line)
+}
+
+// ask returns the answer to the question, or the fallback if it is left empty (required if there is none).
+func (p *prompter) ask(label string, fallback string) string {
+	for {
+		if fallback != "" {
+			lipgloss.Printf("%s %s %s: ", primary.Render("?"), label, secondary.Render("["+fallback+"]"))
+		} else {
+			lipgloss.Printf("%s %s: ", primary.Render("?"), label)
+		}
+
+		if answer := p.line(); answer != "" {
+			return answer
+		}
+		if fallback != "" {
+			return fallback
+		}
+	}
+}
+
+// optional returns the answer to the question, which may be left empty.
+func (p *prompter) optional(label string) string {
+	lipgloss.Printf("%s %s %s: ", primary.Render("?"), label, secondary.Render("(optional)"))
+	return p.line()
+}
+
+// secret returns the answer to the question without echoing it on terminals.
+func (p *prompter) secret(label string) string {
+	for {
+		lipgloss.Printf("%s %s: ", primary.Render("?"), label)
+
+		var answer string
+		if term.IsTerminal(os.Stdin.Fd()) {
+			content, err := term.ReadPassword(os.Stdin.Fd())
+			fmt.Println()
+			if err != nil {
+				log.Fatalf("error reading answer: %s\n", utils.GetErrorMessage(err))
+			}
+			answer = strings.TrimSpace(string(content))
+		} else {
+			answer = p.line()
+		}
+
+		if answer != "" {
+			return answer
+		}
+	}
+}
+
+// confirm returns whether the question is answered with yes.
+func (p *prompter) confirm(label string, fallback bool) bool {
+	options := "y/N"
+	if fallback {
+		options = "Y/n"
+	}
+
+	for {
+		lipgloss.Printf("%s %s %s: ", primary.Render("?"), label, secondary.Render("["+options+"]"))
+
+		switch strings.ToLower(p.line()) {
+		case "":
+			return fallback
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+	}
+}
+
+// choose returns the index of the option picked by its number.
+func (p *prompter) choose(label string, options []string) int {
+	for i, option := range options {
+		lipgloss.Printf("  %s %s\n", secondary.Render(fmt.Sprintf("%d)", i+1)), option)
+	}
+
+	for {
+		index, err := strconv.Atoi(p.ask(label, "1"))
+		if err == nil && index >= 1 && index <= len(options) {
+			return index - 1
+		}
+	}
+}
+
+// mapping builds a spec keeping the order of its fields (unlike a map), values are encoded as they are.
+func mapping(fields ...any) yaml.Node {
+	node := yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i < len(fields); i += 2 {
+		var key, value yaml.Node
+		key.Encode(fields[i])
+		value.Encode(fields[i+1])
+		node.Content = append(node.Content, &key, &value)
+	}
+	return node
+}
+
+// jiraSpec asks for the fields of a Jira spec, the email only selects basic authentication (Jira Cloud).
+func jiraSpec(p *prompter, label string) yaml.Node {
+	url := p.ask(label+" URL", "")
+	email := p.optional(label + " email, for Jira Cloud")
+	token := p.secret(label + " token")
+
+	if email == "" {
+		return mapping("url", url, "token", token)
+	}
+	return mapping("url", url, "email", email, "token", token)
+}
+
+func togglTrackSpec(ctx context.Context, p *prompter) (yaml.Node, error) {
+	spec := entries.TimeEntrySourceKinds["TogglTrack"].Spec().(*entries.TogglTrackSpec)
+	spec.Token = p.secret("TogglTrack API token")
+
+	workspaces, err := entries.ListTogglTrackWorkspaces(ctx, *spec)
+	if err != nil {
+		return yaml.Node{}, err
+	}
+	if len(workspaces) == 0 {
+		return yaml.Node{}, fmt.Errorf("no TogglTrack workspace is available with the token")
+	}
+
+	var options []string
+	for _, workspace := range workspaces {
+		options = append(options, fmt.Sprintf("%s %s", workspace.Name, secondary.Render(fmt.Sprintf("(%d)", workspace.Id))))
+	}
+	workspace := workspaces[p.choose("TogglTrack workspace", options)]
+
+	return mapping("workspace", workspace.Id, "token", spec.Token), nil
+}
+
+func sourceSpec(ctx context.Context, p *prompter, kind string) (yaml.Node, error) {
+	switch kind {
+	case "TogglTrack":
+		return togglTrackSpec(ctx, p)
+	case "Clockify":
+		return mapping("workspace", p.ask("Clockify workspace ID", ""), "token", p.secret("Clockify API key")), nil
+	default:
+		return yaml.Node{}, nil
+	}
+}
+
+func targetSpec(p *prompter, kind string) (yaml.Node, error) {
+	switch kind {
+	case "CapsysKronos":
+		return mapping("token", p.secret("CapsysKronos token")), nil
+	case "JiraWorklog":
+		return jiraSpec(p, "Jira"), nil
+	case "Tempo":
+		account := p.ask("Tempo account", "")
+		token := p.secret("Tempo token")
+		jira := jiraSpec(p, "Jira (to resolve issues)")
+		return mapping("account", account, "token", token, "jira", &jira), nil
+	default:
+		return yaml.Node{}, nil
+	}
+}
+
+// verified asks for the spec until the created source or target is able to verify its credentials (by an
+// authenticated request), or until the user keeps it anyway.
+func verified[T any](ctx context.Context, p *prompter, ask func() (yaml.Node, error), create func(spec yaml.Node) (T, error)) yaml.Node {
+	for {
+		spec, err := ask()
+		if err == nil {
+			var created T
+			if created, err = create(spec); err == nil {
+				if verifier, ok := any(created).(entries.Verifier); ok {
+					err = verifier.Verify(ctx)
+				}
+			}
+		}
+
+		if err == nil {
+			lipgloss.Printf("%s %s\n", success.Render("⏺"), "credentials are valid")
+			return spec
+		}
+
+		lipgloss.Printf("%s %s\n", danger.Render("⏺"), utils.GetErrorMessage(err))
+		if spec.Kind != 0 && !p.confirm("Try again?", true) {
+			return spec
+		}
+	}
+}
+
+func configInit(context *cli.Context, path string) {
+	p := &prompter{reader: bufio.NewReader(os.Stdin)}
+
+	// The terminal is saved before secrets are read with echo disabled, as exiting skips restoring it
+	var state *term.State
+	if term.IsTerminal(os.Stdin.Fd()) {
+		state, _ = term.GetState(os.Stdin.Fd())
+	}
+
+	// Nothing is written until every question is answered, so an interrupt simply leaves
+	go func() {
+		<-context.Ctx.Done()
+		if state != nil {
+			term.Restore(os.Stdin.Fd(), state)
+		}
+		fmt.Println()
+		os.Exit(constants.EXIT_INTERRUPTED)
+	}()
+
+	if _, err := os.Stat(path); err == nil {
+		if !p.confirm(fmt.Sprintf("%s already exists, overwrite it?", path), false) {
+			os.Exit(constants.EXIT_ERROR)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		log.Fatalf("error reading config: %s\n", utils.GetErrorMessage(err))
+	}
+
+	sources := slices.Sorted(maps.Keys(entries.TimeEntrySourceKinds))
+	source := entries.TimeEntrySourceConfig{Kind: sources[p.choose("Source kind", sources)]}
+	source.Spec = verified(
+		context.Ctx,
+		p,
+		func() (yaml.Node, error) { return sourceSpec(context.Ctx, p, source.Kind) },
+		func(spec yaml.Node) (entries.TimeEntrySource, error) {
+			return entries.NewTimeEntrySource(entries.TimeEntrySourceConfig{Kind: source.Kind, Spec: spec})
+		},
+	)
+
+	targets := slices.Sorted(maps.Keys(entries.TimeEntryTargetKinds))
+	target := entries.TimeEntryTargetConfig{Kind: targets[p.choose("Target kind", targets)]}
+	target.Spec = verified(
+		context.Ctx,
+		p,
+		func() (yaml.Node, error) { return targetSpec(p, target.Kind) },
+		func(spec yaml.Node) (entries.TimeEntryTarget, error) {
+			return entries.NewTimeEntryTarget(entries.TimeEntryTargetConfig{Kind: target.Kind, Spec: spec}, time.Local, nil)
+		},
+	)
+
+	var timezone string
+	for {
+		timezone = p.ask("Timezone", "Local")
+		if _, err := time.LoadLocation(timezone); err == nil {
+			break
+		}
+		lipgloss.Printf("%s %s\n", danger.Render("⏺"), fmt.Sprintf("unknown timezone: %s", timezone))
+	}
+
+	config := &cfg.Config{Source: &source, Target: &target, TimeZone: &timezone}
+	if err := cfg.Write(path, config); err != nil {
+		log.Fatalf("error writing config: %s\n", utils.GetErrorMessage(err))
+	}
+
+	lipgloss.Printf("%s %s\n", success.Render("⏺"), fmt.Sprintf("configuration written to %s", path))
+	lipgloss.Printf("%s\n", secondary.Render("The tokens are stored in plain text, consider replacing them by ${NAME} references or token_command (see timesheets config --help)."))
+}
+
+func ConfigInit(args []string, context *cli.Context) {
+	command := &cli.FlagSet{FlagSet: flag.NewFlagSet("config init", flag.ContinueOnError)}
+
+	command.Usage = func() {
+		fmt.Printf(`Usage: timesheets config init
+
+Create the configuration interactively.
+
+Asks for the kind of the source and the target and for their tokens. The
+TogglTrack workspaces available with the token are listed to pick one from,
+the tokens of the other kinds are verified by an authenticated request. The
+configuration is written to the --config path (by default %s),
+readable only by the user.
+
+Example (create the configuration at a custom path):
+
+  timesheets --config ./config.yaml config init
+
+For more information, visit: https://github.com/tornermarton/timesheets
+`, cfg.GetDefaultPath())
+	}
+
+	command.Parse(args)
+	if command.NArg() > 0 {
+		command.Usage()
+		os.Exit(constants.EXIT_ERROR)
+	}
+
+	path := context.ConfigPath
+	if path == "" {
+		path = cfg.GetDefaultPath()
+	}
+
+	configInit(context, path)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tornermarton/timesheets/internal/cli"
+	cfg "github.com/tornermarton/timesheets/internal/config"
+	"github.com/tornermarton/timesheets/internal/constants"
+)
+
+func configSchema() {
+	printJson(cfg.Schema(), true)
+}
+
+func ConfigSchema(args []string, context *cli.Context) {
+	command := &cli.FlagSet{FlagSet: flag.NewFlagSet("config schema", flag.ContinueOnError)}
+
+	command.Usage = func() {
+		fmt.Printf(`Usage: timesheets config schema
+
+Print the JSON Schema of the configuration file.
+
+The schema covers every supported kind of source, target and transform (the
+spec is selected by the kind) including their defaults, so editors supporting
+JSON Schemas offer completion and validation while editing the configuration.
+
+Example (use the schema with the YAML language server):
+
+  timesheets config schema > ~/.config/timesheets/schema.json
+
+  # yaml-language-server: $schema=./schema.json
+  source:
+    kind: TogglTrack
+    ...
+
+For more information, visit: https://github.com/tornermarton/timesheets
+`)
+	}
+
+	command.Parse(args)
+	if command.NArg() > 0 {
+		command.Usage()
+		os.Exit(constants.EXIT_ERROR)
+	}
+
+	configSchema()
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+
+	"github.com/tornermarton/timesheets/internal/cli"
+	"github.com/tornermarton/timesheets/internal/constants"
+)
+
+func configValidate(context *cli.Context) {
+	if err := context.Config.Validate(); err != nil {
+		for _, problem := range strings.Split(err.Error(), "\n") {
+			lipgloss.Fprintf(os.Stderr, "%s %s\n", danger.Render("⏺"), problem)
+		}
+		os.Exit(constants.EXIT_ERROR)
+	}
+
+	lipgloss.Printf("%s %s\n", success.Render("⏺"), "configuration is valid")
+}
+
+func ConfigValidate(args []string, context *cli.Context) {
+	command := &cli.FlagSet{FlagSet: flag.NewFlagSet("config validate", flag.ContinueOnError)}
+
+	command.Usage = func() {
+		fmt.Printf(`Usage: timesheets config validate
+
+Validate the used configuration, reporting every problem with its line.
+
+Specs are decoded according to their kind: unknown fields (e.g. typos like
+activityTypeID), values of the wrong type (e.g. workspace: "123") and invalid
+values are all reported, instead of only the first one. Exits with 1 if the
+configuration has any problem, so it can be used to lint configurations.
+
+Example (validate a configuration before committing it):
+
+  timesheets --config ./config.yaml config validate
+
+For more information, visit: https://github.com/tornermarton/timesheets
+`)
+	}
+
+	command.Parse(args)
+	if command.NArg() > 0 {
+		command.Usage()
+		os.Exit(constants.EXIT_ERROR)
+	}
+
+	configValidate(context)
+}
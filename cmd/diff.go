@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"charm.land/lipgloss/v2"
+
+	"github.com/tornermarton/timesheets/internal/arrays"
+	"github.com/tornermarton/timesheets/internal/cli"
+	cfg "github.com/tornermarton/timesheets/internal/config"
+	"github.com/tornermarton/timesheets/internal/constants"
+	"github.com/tornermarton/timesheets/internal/entries"
+	"github.com/tornermarton/timesheets/internal/ledger"
+	"github.com/tornermarton/timesheets/internal/utils"
+)
+
+func compare(expected entries.TimeEntry, actual entries.TimeEntry) []string {
+	var differences []string
+
+	if expected.Issue != actual.Issue {
+		differences = append(differences, fmt.Sprintf("issue %s ≠ %s", expected.Issue, actual.Issue))
+	}
+
+	if !expected.From.Equal(actual.From) {
+		differences = append(differences, fmt.Sprintf("start %s ≠ %s", expected.From.Format(time.TimeOnly), actual.From.Format(time.TimeOnly)))
+	}
+
+	if expectedDuration, actualDuration := expected.Till.Sub(expected.From), actual.Till.Sub(actual.From); expectedDuration != actualDuration {
+		differences = append(differences, fmt.Sprintf("duration %s ≠ %s", expectedDuration, actualDuration))
+	}
+
+	if expected.Description != actual.Description {
+		differences = append(differences, fmt.Sprintf("comment %q ≠ %q", expected.Description, actual.Description))
+	}
+
+	var keys []string
+	for key := range expected.Fields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if fmt.Sprint(expected.Fields[key]) != fmt.Sprint(actual.Fields[key]) {
+			differences = append(differences, fmt.Sprintf("%s %v ≠ %v", key, expected.Fields[key], actual.Fields[key]))
+		}
+	}
+
+	return differences
+}
+
+// comparison is an entry of the source compared with the worklogs of the target, or a worklog of the target
+// without an entry.
+type comparison struct {
+	// status is matching, missing, different or extra
+	status      string
+	entry       entries.TimeEntry
+	differences []string
+}
+
+// pair compares the (mapped) entries with the worklogs of the target. Entries are paired by the ledger first,
+// the others (unrecorded, or recorded with a worklog no longer present) by issue and start as a fallback.
+// The worklogs left without an entry are extra.
+func pair(target string, expected []entries.TimeEntry, actual []entries.TimeEntry, ledger_ records) []comparison {
+	worklogs := map[string]entries.TimeEntry{}
+	for _, worklog := range actual {
+		worklogs[worklog.Id] = worklog
+	}
+
+	find := func(entry entries.TimeEntry) (entries.TimeEntry, bool) {
+		if record, ok := ledger_.Get(target, entry.Id); ok {
+			if worklog, ok := worklogs[record.Worklog]; ok {
+				return worklog, true
+			}
+		}
+
+		for _, worklog := range actual {
+			if _, ok := worklogs[worklog.Id]; ok && worklog.Issue == entry.Issue && worklog.From.Equal(entry.From) {
+				return worklog, true
+			}
+		}
+
+		return entries.TimeEntry{}, false
+	}
+
+	var comparisons []comparison
+
+	for _, entry := range expected {
+		worklog, ok := find(entry)
+		if !ok {
+			comparisons = append(comparisons, comparison{status: "missing", entry: entry})
+			continue
+		}
+		delete(worklogs, worklog.Id)
+
+		if differences := compare(entry, worklog); len(differences) > 0 {
+			comparisons = append(comparisons, comparison{status: "different", entry: entry, differences: differences})
+		} else {
+			comparisons = append(comparisons, comparison{status: "matching", entry: entry})
+		}
+	}
+
+	for _, worklog := range actual {
+		if _, ok := worklogs[worklog.Id]; ok {
+			comparisons = append(comparisons, comparison{status: "extra", entry: worklog})
+		}
+	}
+
+	return comparisons
+}
+
+func diff(context *cli.Context, location *time.Location, from time.Time, till time.Time, name string) {
+	source, err := entries.NewTimeEntrySources(context.Config.GetSources())
+	if err != nil {
+		fatalf(context, "error creating time entry source: %s\n", utils.GetErrorMessage(err))
+	}
+
+	cache, err := context.Config.GetCache()
+	if err != nil {
+		fatalf(context, "error opening cache: %s\n", utils.GetErrorMessage(err))
+	}
+	// Exiting on an error skips the deferred calls, the lookups done so far are saved then too
+	atExit = append(atExit, func() { saveCache(cache) })
+	defer saveCache(cache)
+
+	targets, err := entries.NewTimeEntryTargets(context.Config.GetTargets(), location, cache)
+	if err != nil {
+		fatalf(context, "error creating time entry target: %s\n", utils.GetErrorMessage(err))
+	}
+
+	index := 0
+	if name != "" {
+		index = slices.IndexFunc(targets, func(target entries.NamedTimeEntryTarget) bool { return target.Name == name })
+		if index < 0 {
+			fatalf(context, "error selecting time entry target: unknown target %s\n", name)
+		}
+	}
+	target := targets[index]
+
+	transforms, err := entries.NewTimeEntryTransforms(context.Config.Transforms, location)
+	if err != nil {
+		fatalf(context, "error creating time entry transform: %s\n", utils.GetErrorMessage(err))
+	}
+
+	ledger_, err := ledger.Open(utils.Coalesce(context.Config.Ledger, cfg.GetDefaultLedgerPath()))
+	if err != nil {
+		fatalf(context, "error opening ledger: %s\n", utils.GetErrorMessage(err))
+	}
+
+	expected, err := source.PullTimeEntries(context.Ctx, from.Add(-transforms.Lookback()), till)
+	if err != nil {
+		fatalf(context, "error pulling time entries: %s\n", utils.GetErrorMessage(err))
+	}
+
+	expected, err = transforms.TransformTimeEntries(expected)
+	if err != nil {
+		fatalf(context, "error transforming time entries: %s\n", utils.GetErrorMessage(err))
+	}
+
+	// Entries (or their pieces) belong to the period their start falls into
+	expected = arrays.Filter(expected, func(entry entries.TimeEntry) bool { return entry.StartsWithin(from, till) })
+	expected = arrays.Filter(expected, target.Filter.Matches)
+
+	actual, err := target.ListTimeEntries(context.Ctx, from, till)
+	if err != nil {
+		fatalf(context, "error listing worklogs: %s\n", utils.GetErrorMessage(err))
+	}
+
+	mapped := arrays.Map(expected, target.MapTimeEntry)
+
+	for _, comparison := range pair(target.Name, mapped, actual, ledger_) {
+		switch comparison.status {
+		case "missing":
+			lipgloss.Printf("%s %s %s\n", danger.Render("⏺"), comparison.entry.String(location), secondary.Render("(missing)"))
+		case "different":
+			lipgloss.Printf("%s %s %s\n", warning.Render("⏺"), comparison.entry.String(location), secondary.Render("(different)"))
+			lipgloss.Printf("╰─ %s\n\n", warning.Render(strings.Join(comparison.differences, ", ")))
+		case "extra":
+			lipgloss.Printf("%s %s %s\n", warning.Render("⏺"), comparison.entry.String(location), secondary.Render("(extra)"))
+		default:
+			lipgloss.Printf("%s %s\n", success.Render("⏺"), comparison.entry.String(location))
+		}
+	}
+}
+
+func Diff(args []string, context *cli.Context) {
+	command := &cli.FlagSet{FlagSet: flag.NewFlagSet("diff", flag.ContinueOnError)}
+
+	location := getLocation(context)
+
+	fromFlag := command.Time("from", today(location), "date/datetime to compare work logs from (inclusive)")
+	tillFlag := command.Time("till", today(location).AddDate(0, 0, 1), "date/datetime to compare work logs till (exclusive)")
+
+	targetFlag := command.String("target", "", "name of the target to compare with (defaults to the first one)")
+
+	command.Usage = func() {
+		fmt.Printf(`Usage: timesheets diff [options]
+
+Compare the work logs of the source with the worklogs of the target.
+
+Entries are reported as missing (not in the target yet), different (present in
+the target with other values) or extra (only present in the target). Only the
+entries matching the filter of the target are compared.
+
+Options:
+
+`)
+		command.PrintDefaults()
+		fmt.Printf(`
+Example (compare work logs of June 2025):
+
+  timesheets diff --from 2025-06-01 --till 2025-07-01
+
+For more information, visit: https://github.com/tornermarton/timesheets
+`)
+	}
+
+	command.Parse(args)
+	if command.NArg() > 0 {
+		command.Usage()
+		os.Exit(constants.EXIT_ERROR)
+	}
+
+	diff(context, location, *fromFlag, *tillFlag, *targetFlag)
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/tornermarton/timesheets/internal/entries"
+	"github.com/tornermarton/timesheets/internal/ledger"
+)
+
+// worklog returns the entry as listed by the target, identified by the worklog.
+func worklog(id string, entry entries.TimeEntry) entries.TimeEntry {
+	entry.Id = id
+	return entry
+}
+
+func TestPair(t *testing.T) {
+	moved := newEntry("1", 13)
+	changed := newEntry("1", 9)
+	changed.Description = "Review"
+
+	tests := []struct {
+		name     string
+		records  []ledger.Record
+		expected []entries.TimeEntry
+		actual   []entries.TimeEntry
+		statuses []string
+	}{
+		{
+			name:     "matching by ledger",
+			records:  []ledger.Record{record("Kronos", "w-1", newEntry("1", 9))},
+			expected: []entries.TimeEntry{newEntry("1", 9)},
+			actual:   []entries.TimeEntry{worklog("w-2", newEntry("1", 9)), worklog("w-1", newEntry("1", 9))},
+			statuses: []string{"1:matching", "w-2:extra"},
+		},
+		{
+			name:     "different by ledger",
+			records:  []ledger.Record{record("Kronos", "w-1", newEntry("1", 9))},
+			expected: []entries.TimeEntry{newEntry("1", 9)},
+			actual:   []entries.TimeEntry{worklog("w-1", moved)},
+			statuses: []string{"1:different"},
+		},
+		{
+			name:     "matching by issue and start",
+			expected: []entries.TimeEntry{newEntry("1", 9), newEntry("2", 10)},
+			actual:   []entries.TimeEntry{worklog("w-2", newEntry("2", 10)), worklog("w-1", changed)},
+			statuses: []string{"1:different", "2:matching"},
+		},
+		{
+			name:     "recorded worklog deleted",
+			records:  []ledger.Record{record("Kronos", "w-1", newEntry("1", 9)), record("Kronos", "w-2", newEntry("2", 10))},
+			expected: []entries.TimeEntry{newEntry("1", 9), newEntry("2", 10)},
+			actual:   []entries.TimeEntry{worklog("w-3", newEntry("1", 9))},
+			statuses: []string{"1:matching", "2:missing"},
+		},
+		{
+			name:     "missing and extra",
+			expected: []entries.TimeEntry{newEntry("1", 9)},
+			actual:   []entries.TimeEntry{worklog("w-1", moved)},
+			statuses: []string{"1:missing", "w-1:extra"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var statuses []string
+			for _, comparison := range pair("Kronos", test.expected, test.actual, newFakeLedger(test.records...)) {
+				statuses = append(statuses, comparison.entry.Id+":"+comparison.status)
+			}
+
+			if !slices.Equal(statuses, test.statuses) {
+				t.Errorf("got %v, want %v", statuses, test.statuses)
+			}
+		})
+	}
+}
+
+func TestPairDifferences(t *testing.T) {
+	changed := newEntry("1", 9)
+	changed.Description = "Review"
+	changed.Fields = map[string]any{"activityTypeId": 5}
+
+	expected := newEntry("1", 9)
+	expected.Fields = map[string]any{"activityTypeId": 12}
+
+	comparisons := pair("Kronos", []entries.TimeEntry{expected}, []entries.TimeEntry{worklog("w-1", changed)}, newFakeLedger())
+
+	if differences := comparisons[0].differences; !slices.Equal(differences, []string{`comment "Work" ≠ "Review"`, "activityTypeId 12 ≠ 5"}) {
+		t.Errorf("unexpected differences: %v", differences)
+	}
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"charm.land/lipgloss/v2"
+
+	"github.com/tornermarton/timesheets/internal/arrays"
+	"github.com/tornermarton/timesheets/internal/cli"
+	"github.com/tornermarton/timesheets/internal/constants"
+	"github.com/tornermarton/timesheets/internal/entries"
+	"github.com/tornermarton/timesheets/internal/utils"
+)
+
+// total sums the duration of the entries sharing a key (e.g. the issue), keeping their descriptions and tags.
+type total struct {
+	Key          string
+	Duration     time.Duration
+	Descriptions []string
+	Tags         []string
+}
+
+// totals groups the entries by the keys returned for them, an entry counts towards every key (e.g. all of its tags).
+func totals(entries_ []entries.TimeEntry, keys func(entries.TimeEntry) []string) []total {
+	var out []total
+	indexes := map[string]int{}
+
+	for _, entry := range entries_ {
+		for _, key := range keys(entry) {
+			i, ok := indexes[key]
+			if !ok {
+				i = len(out)
+				indexes[key] = i
+				out = append(out, total{Key: key})
+			}
+
+			out[i].Duration += entry.Till.Sub(entry.From)
+			if entry.Description != "" && !slices.Contains(out[i].Descriptions, entry.Description) {
+				out[i].Descriptions = append(out[i].Descriptions, entry.Description)
+			}
+			for _, tag := range entry.Tags {
+				if !slices.Contains(out[i].Tags, tag) {
+					out[i].Tags = append(out[i].Tags, tag)
+				}
+			}
+		}
+	}
+
+	slices.SortStableFunc(out, func(a total, b total) int { return strings.Compare(a.Key, b.Key) })
+
+	return out
+}
+
+// day is the total of a day of the report, along with the duration expected to be worked on it.
+type day struct {
+	total
+	Date     time.Time
+	Expected time.Duration
+}
+
+// days totals the entries per day in the location. With expectations every weekday of the period is reported, so
+// days without any entries stand out as well.
+func days(entries_ []entries.TimeEntry, location *time.Location, from time.Time, till time.Time, expected time.Duration) []day {
+	daily := totals(entries_, func(entry entries.TimeEntry) []string {
+		return []string{entry.From.In(location).Format(time.DateOnly)}
+	})
+
+	if expected > 0 {
+		for date := from.In(location); date.Before(till); date = date.AddDate(0, 0, 1) {
+			key := date.Format(time.DateOnly)
+			if date.Weekday() != time.Saturday && date.Weekday() != time.Sunday && !slices.ContainsFunc(daily, func(t total) bool { return t.Key == key }) {
+				daily = append(daily, total{Key: key})
+			}
+		}
+		slices.SortFunc(daily, func(a total, b total) int { return strings.Compare(a.Key, b.Key) })
+	}
+
+	out := make([]day, 0, len(daily))
+	for _, total := range daily {
+		date, _ := time.ParseInLocation(time.DateOnly, total.Key, location)
+
+		var expectedDay time.Duration
+		if date.Weekday() != time.Saturday && date.Weekday() != time.Sunday {
+			expectedDay = expected
+		}
+
+		out = append(out, day{total: total, Date: date, Expected: expectedDay})
+	}
+
+	return out
+}
+
+func formatDuration(duration time.Duration) string {
+	return utils.FitString(duration.Round(time.Minute).String(), 9)
+}
+
+// formatDeviation renders the difference from the expected duration, highlighting under- and overbooking.
+func formatDeviation(actual time.Duration, expected time.Duration) string {
+	deviation := (actual - expected).Round(time.Minute)
+
+	out := secondary.Render("/ "+expected.Round(time.Minute).String()) + " "
+	switch {
+	case deviation < 0:
+		return out + warning.Render(fmt.Sprintf("(%s under)", -deviation))
+	case deviation > 0:
+		return out + danger.Render(fmt.Sprintf("(%s over)", deviation))
+	default:
+		return out + success.Render("(as expected)")
+	}
+}
+
+func report(context *cli.Context, location *time.Location, from time.Time, till time.Time, expected time.Duration) {
+	source, err := entries.NewTimeEntrySources(context.Config.GetSources())
+	if err != nil {
+		fatalf(context, "error creating time entry source: %s\n", utils.GetErrorMessage(err))
+	}
+
+	transforms, err := entries.NewTimeEntryTransforms(context.Config.Transforms, location)
+	if err != nil {
+		fatalf(context, "error creating time entry transform: %s\n", utils.GetErrorMessage(err))
+	}
+
+	entries_, err := source.PullTimeEntries(context.Ctx, from.Add(-transforms.Lookback()), till)
+	if err != nil {
+		fatalf(context, "error pulling time entries: %s\n", utils.GetErrorMessage(err))
+	}
+
+	entries_, err = transforms.TransformTimeEntries(entries_)
+	if err != nil {
+		fatalf(context, "error transforming time entries: %s\n", utils.GetErrorMessage(err))
+	}
+
+	// Entries (or their pieces) belong to the period their start falls into
+	entries_ = arrays.Filter(entries_, func(entry entries.TimeEntry) bool { return entry.StartsWithin(from, till) })
+
+	days_ := days(entries_, location, from, till, expected)
+
+	var sum, expectedSum time.Duration
+	for _, day := range days_ {
+		sum += day.Duration
+		expectedSum += day.Expected
+
+		line := fmt.Sprintf("%s %s %s", primary.Render(day.Date.Format("2006-01-02 Mon")), secondary.Render("·"), primary.Render(day.Duration.Round(time.Minute).String()))
+		if expected > 0 {
+			line += " " + formatDeviation(day.Duration, day.Expected)
+		}
+		lipgloss.Println(line)
+
+		inDay := arrays.Filter(entries_, func(entry entries.TimeEntry) bool { return entry.From.In(location).Format(time.DateOnly) == day.Key })
+		for _, issue := range totals(inDay, func(entry entries.TimeEntry) []string { return []string{entry.Issue} }) {
+			lipgloss.Printf(
+				"  %s %s %s %s\n",
+				secondary.Render(formatDuration(issue.Duration)),
+				primary.Render(utils.FitString(issue.Key, 12)),
+				primary.Render(utils.FitString(strings.Join(issue.Descriptions, "; "), 20)),
+				secondary.Render(utils.FitArray(issue.Tags, 20)),
+			)
+		}
+
+		lipgloss.Println()
+	}
+
+	lipgloss.Println(primary.Render("Issues"))
+	for _, issue := range totals(entries_, func(entry entries.TimeEntry) []string { return []string{entry.Issue} }) {
+		lipgloss.Printf("  %s %s\n", secondary.Render(formatDuration(issue.Duration)), primary.Render(issue.Key))
+	}
+	lipgloss.Println()
+
+	lipgloss.Println(primary.Render("Tags"))
+	for _, tag := range totals(entries_, func(entry entries.TimeEntry) []string { return entry.Tags }) {
+		lipgloss.Printf("  %s %s\n", secondary.Render(formatDuration(tag.Duration)), primary.Render(tag.Key))
+	}
+	lipgloss.Println()
+
+	line := fmt.Sprintf("%s %s %s", primary.Render("Total"), secondary.Render("·"), primary.Render(sum.Round(time.Minute).String()))
+	if expected > 0 {
+		line += " " + formatDeviation(sum, expectedSum)
+	}
+	lipgloss.Println(line)
+}
+
+func Report(args []string, context *cli.Context) {
+	command := &cli.FlagSet{FlagSet: flag.NewFlagSet("report", flag.ContinueOnError)}
+
+	location := getLocation(context)
+
+	fromFlag := command.Time("from", today(location), "date/datetime to report work logs from (inclusive)")
+	tillFlag := command.Time("till", today(location).AddDate(0, 0, 1), "date/datetime to report work logs till (exclusive)")
+
+	expectedFlag := command.Duration("expected", 0, "expected duration of work per weekday (e.g. 8h) to highlight under- and overbooking")
+
+	command.Usage = func() {
+		fmt.Printf(`Usage: timesheets report [options]
+
+Report the work logs of the source, as they would be synchronized.
+
+Entries are pulled and transformed the same way as by sync, then totaled per
+day (and issue within the day), per issue and per tag. Entries with multiple
+tags count towards each of their tags.
+
+With --expected every weekday of the period is reported, even without entries,
+and the totals are compared with the expected duration. Weekends are expected
+to be free, but reported whenever work was logged.
+
+Options:
+
+`)
+		command.PrintDefaults()
+		fmt.Printf(`
+Example (report work logs of June 2025 expecting 8 hours per weekday):
+
+  timesheets report --from 2025-06-01 --till 2025-07-01 --expected 8h
+
+For more information, visit: https://github.com/tornermarton/timesheets
+`)
+	}
+
+	command.Parse(args)
+	if command.NArg() > 0 || *expectedFlag < 0 {
+		command.Usage()
+		os.Exit(constants.EXIT_ERROR)
+	}
+
+	report(context, location, *fromFlag, *tillFlag, *expectedFlag)
+}
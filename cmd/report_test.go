@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tornermarton/timesheets/internal/entries"
+)
+
+func TestDays(t *testing.T) {
+	location, _ := time.LoadLocation("Europe/Budapest")
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2025, 6, day, hour, minute, 0, 0, location)
+	}
+
+	// The period is from Friday till Tuesday (inclusive) in the timezone of the report
+	from, till := at(6, 0, 0), at(11, 0, 0)
+	entries_ := []entries.TimeEntry{
+		{Id: "1", Issue: "ABC-1", From: at(6, 9, 0), Till: at(6, 15, 0)},
+		{Id: "2", Issue: "ABC-1", From: at(7, 0, 30), Till: at(7, 1, 30)}, // Friday in UTC
+		{Id: "3", Issue: "ABC-2", From: at(9, 9, 0), Till: at(9, 17, 0)},
+	}
+
+	days_ := days(entries_, location, from, till, 8*time.Hour)
+
+	var keys []string
+	var sum, expectedSum time.Duration
+	for _, day := range days_ {
+		keys = append(keys, day.Key)
+		sum += day.Duration
+		expectedSum += day.Expected
+	}
+
+	if !slices.Equal(keys, []string{"2025-06-06", "2025-06-07", "2025-06-09", "2025-06-10"}) {
+		t.Fatalf("expected the weekdays and the days with entries, got %v", keys)
+	}
+	if days_[0].Duration != 6*time.Hour || days_[1].Duration != time.Hour || days_[1].Expected != 0 || days_[3].Duration != 0 {
+		t.Errorf("unexpected daily totals: %+v", days_)
+	}
+	if !days_[0].Date.Equal(from) {
+		t.Errorf("expected the date to be midnight in the location, got %s", days_[0].Date)
+	}
+	if sum != 15*time.Hour || expectedSum-sum != 9*time.Hour {
+		t.Errorf("expected 15h worked and 9h short, got %s and %s", sum, expectedSum-sum)
+	}
+
+	// Without expectations only the days with entries are reported
+	if days_ := days(entries_, location, from, till, 0); len(days_) != 3 || days_[0].Expected != 0 {
+		t.Errorf("expected the days with entries only, got %+v", days_)
+	}
+}
@@ -1,17 +1,29 @@
 package cmd
 
 import (
+	stdcontext "context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/term"
 
+	"github.com/tornermarton/timesheets/internal/arrays"
+	"github.com/tornermarton/timesheets/internal/cache"
 	"github.com/tornermarton/timesheets/internal/cli"
+	cfg "github.com/tornermarton/timesheets/internal/config"
 	"github.com/tornermarton/timesheets/internal/constants"
 	"github.com/tornermarton/timesheets/internal/entries"
+	"github.com/tornermarton/timesheets/internal/ledger"
 	"github.com/tornermarton/timesheets/internal/utils"
 )
 
@@ -19,63 +31,552 @@ import (
 var status = lipgloss.NewStyle().Foreground(lipgloss.BrightWhite)
 var success = lipgloss.NewStyle().Foreground(lipgloss.Green)
 var danger = lipgloss.NewStyle().Foreground(lipgloss.Red)
+var warning = lipgloss.NewStyle().Foreground(lipgloss.Yellow)
+var primary = lipgloss.NewStyle().Bold(true)
+var secondary = lipgloss.NewStyle().Faint(true)
 
-func sync(context *cli.Context, from time.Time, till time.Time, bail bool, dry bool) {
-	source, err := entries.NewTimeEntrySource(context.Config.Source)
+func record(target string, worklog string, entry entries.TimeEntry) ledger.Record {
+	return ledger.Record{
+		Target:      target,
+		Entry:       entry.Id,
+		Worklog:     worklog,
+		Checksum:    entry.Checksum(),
+		Issue:       entry.Issue,
+		Description: entry.Description,
+		From:        entry.From,
+		Till:        entry.Till,
+	}
+}
+
+// task is a change of a worklog to be applied, tasks without apply only report the state of the entry
+type task struct {
+	target entries.NamedTimeEntryTarget
+	entry  entries.TimeEntry
+	// action is created, updated or deleted for changes, unchanged or removed otherwise
+	action string
+	apply  func(ctx stdcontext.Context) (ledger.Record, bool, error)
+
+	done    chan struct{}
+	worklog string
+	payload any
+	// payloadErr is the error previewing the payload, the change itself may still succeed
+	payloadErr error
+	err        error
+	// skipped tasks were cancelled before being started
+	skipped bool
+}
+
+func newTask(target entries.NamedTimeEntryTarget, entry entries.TimeEntry, action string, apply func(ctx stdcontext.Context) (ledger.Record, bool, error)) *task {
+	return &task{target: target, entry: entry, action: action, apply: apply, done: make(chan struct{})}
+}
+
+type syncResult struct {
+	Type   string `json:"type"`
+	Target string `json:"target"`
+	// Action is create, update or delete for changes, empty otherwise
+	Action string `json:"action,omitempty"`
+	// Status is the outcome, which is the action done (created, updated or deleted) on success
+	Status  string            `json:"status"`
+	Entry   entries.TimeEntry `json:"entry"`
+	Worklog string            `json:"worklog,omitempty"`
+	// Payload is what is sent to the target for the entry, left out for removed and deleted worklogs as
+	// nothing is sent for them (their entry is only known from the ledger)
+	Payload      any    `json:"payload,omitempty"`
+	PayloadError string `json:"payloadError,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+type syncSummary struct {
+	Type   string         `json:"type"`
+	Dry    bool           `json:"dry"`
+	Counts map[string]int `json:"counts"`
+	// Durations sums the duration of the entries by status, in seconds
+	Durations map[string]float64 `json:"durations"`
+	// Elapsed is the duration of the run, in seconds
+	Elapsed  float64 `json:"elapsed"`
+	ExitCode int     `json:"exitCode"`
+}
+
+// syncOutput is the output of sync with --output json, ndjson prints its results and summary line by line
+type syncOutput struct {
+	Results []syncResult `json:"results"`
+	Summary syncSummary  `json:"summary"`
+}
+
+// statuses orders the statuses within the summary line
+var statuses = []string{"created", "updated", "deleted", "unchanged", "removed", "dry", "failed", "cancelled", "skipped"}
+
+func (s syncSummary) String() string {
+	var parts []string
+	for _, status := range statuses {
+		if count, ok := s.Counts[status]; ok {
+			duration := time.Duration(s.Durations[status] * float64(time.Second))
+			parts = append(parts, fmt.Sprintf("%d %s (%s)", count, status, duration))
+		}
+	}
+
+	if len(parts) == 0 {
+		parts = append(parts, "nothing to synchronize")
+	}
+
+	return strings.Join(parts, ", ") + fmt.Sprintf(" in %s", time.Duration(s.Elapsed*float64(time.Second)).Round(time.Millisecond))
+}
+
+var actions = map[string]string{
+	"created": "create",
+	"updated": "update",
+	"deleted": "delete",
+}
+
+func (t *task) result(dry bool) syncResult {
+	result := syncResult{
+		Type:         "entry",
+		Target:       t.target.Name,
+		Action:       actions[t.action],
+		Entry:        t.entry,
+		Worklog:      t.worklog,
+		Payload:      t.payload,
+		PayloadError: utils.GetErrorMessage(t.payloadErr),
+	}
+
+	switch {
+	case t.apply == nil:
+		result.Status = t.action
+	case dry:
+		result.Status = "dry"
+	case t.skipped:
+		result.Status = "skipped"
+	case errors.Is(t.err, stdcontext.Canceled):
+		result.Status = "cancelled"
+	case t.err != nil:
+		result.Status = "failed"
+		result.Error = utils.GetErrorMessage(t.err)
+	default:
+		result.Status = t.action
+	}
+
+	return result
+}
+
+// records is the ledger of the synchronized entries, as used to plan and apply the tasks.
+type records interface {
+	Get(target string, entry string) (ledger.Record, bool)
+	Put(record ledger.Record)
+	Delete(target string, entry string)
+	Records(target string, from time.Time, till time.Time) []ledger.Record
+	Save() error
+}
+
+// plan returns the tasks reconciling the targets with the entries of the period, in the order of the entries
+// followed by the recorded entries removed from the source (which are only deleted with prune).
+func plan(entries_ []entries.TimeEntry, targets []entries.NamedTimeEntryTarget, ledger_ records, from time.Time, till time.Time, prune bool) []*task {
+	var tasks []*task
+
+	for _, entry := range entries_ {
+		for _, target := range targets {
+			if !target.Filter.Matches(entry) {
+				continue
+			}
+
+			existing, ok := ledger_.Get(target.Name, entry.Id)
+			if !ok {
+				tasks = append(tasks, newTask(target, entry, "created", func(ctx stdcontext.Context) (ledger.Record, bool, error) {
+					worklog, err := target.PushTimeEntry(ctx, entry)
+					return record(target.Name, worklog, entry), true, err
+				}))
+				continue
+			}
+
+			if existing.Checksum == entry.Checksum() {
+				tasks = append(tasks, &task{target: target, entry: entry, action: "unchanged", worklog: existing.Worklog})
+				continue
+			}
+
+			tasks = append(tasks, newTask(target, entry, "updated", func(ctx stdcontext.Context) (ledger.Record, bool, error) {
+				worklog, err := target.UpdateTimeEntry(ctx, existing.Worklog, entry)
+				return record(target.Name, worklog, entry), true, err
+			}))
+		}
+	}
+
+	for _, target := range targets {
+		// Entries no longer routed to the target are handled as if they were removed from the source
+		pulled := map[string]bool{}
+		for _, entry := range entries_ {
+			pulled[entry.Id] = target.Filter.Matches(entry)
+		}
+
+		for _, existing := range ledger_.Records(target.Name, from, till) {
+			if pulled[existing.Entry] {
+				continue
+			}
+
+			entry := entries.TimeEntry{
+				Id:          existing.Entry,
+				Issue:       existing.Issue,
+				From:        existing.From,
+				Till:        existing.Till,
+				Description: existing.Description,
+			}
+
+			if !prune {
+				tasks = append(tasks, &task{target: target, entry: entry, action: "removed", worklog: existing.Worklog})
+				continue
+			}
+
+			tasks = append(tasks, newTask(target, entry, "deleted", func(ctx stdcontext.Context) (ledger.Record, bool, error) {
+				return existing, false, target.DeleteTimeEntry(ctx, existing.Worklog)
+			}))
+		}
+	}
+
+	return tasks
+}
+
+// execute applies the tasks on the given number of workers in the background, recording every change in the
+// ledger. Interrupting (cancelling ctx) cancels the requests in flight, bailing only stops starting new ones:
+// a cancelled request may still have created a worklog, which would then be missing from the ledger and
+// booked again.
+func execute(ctx stdcontext.Context, tasks []*task, ledger_ records, parallel int, bail bool) {
+	var stopped atomic.Bool
+
+	run := func(task *task) {
+		defer close(task.done)
+
+		if ctx.Err() != nil || stopped.Load() {
+			task.skipped = true
+			return
+		}
+
+		record, keep, err := task.apply(ctx)
+		if err == nil {
+			if keep {
+				ledger_.Put(record)
+			} else {
+				ledger_.Delete(record.Target, record.Entry)
+			}
+
+			if err_ := ledger_.Save(); err_ != nil {
+				err = fmt.Errorf("worklog %s was %s but could not be recorded: %s", record.Worklog, task.action, err_)
+			}
+		}
+
+		if err != nil && bail {
+			stopped.Store(true)
+		}
+
+		task.worklog = record.Worklog
+		task.err = err
+	}
+
+	queue := make(chan *task)
+	for range parallel {
+		go func() {
+			for task := range queue {
+				run(task)
+			}
+		}()
+	}
+
+	go func() {
+		for _, task := range tasks {
+			if task.apply != nil {
+				queue <- task
+			}
+		}
+		close(queue)
+	}()
+}
+
+// collect waits for the tasks in their order, so they are reported in the order of the entries even when
+// applied concurrently, and sums up their results. wait is called with the tasks still running and returns
+// once they are done (e.g. showing progress meanwhile), report is called with the result of every task.
+func collect(ctx stdcontext.Context, tasks []*task, dry bool, preview bool, wait func(task *task), report func(task *task, result syncResult)) syncSummary {
+	summary := syncSummary{Type: "summary", Dry: dry, Counts: map[string]int{}, Durations: map[string]float64{}}
+
+	for _, task := range tasks {
+		if task.apply != nil && !dry {
+			select {
+			case <-task.done:
+			default:
+				wait(task)
+			}
+		}
+
+		if preview && !task.skipped {
+			task.preview(ctx)
+		}
+
+		result := task.result(dry)
+		summary.Counts[result.Status]++
+		summary.Durations[result.Status] += task.entry.Till.Sub(task.entry.From).Seconds()
+
+		report(task, result)
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		summary.ExitCode = constants.EXIT_INTERRUPTED
+	case summary.Counts["failed"] > 0:
+		summary.ExitCode = constants.EXIT_PARTIAL
+	default:
+		summary.ExitCode = constants.EXIT_OK
+	}
+
+	return summary
+}
+
+// preview fills the payload of the entry as sent to the target, the payload is only of interest for machine
+// readable output but may cost requests (e.g. issue lookups).
+func (t *task) preview(ctx stdcontext.Context) {
+	if t.action == "removed" || t.action == "deleted" {
+		return
+	}
+
+	payload, err := t.target.PreviewTimeEntry(ctx, t.entry)
 	if err != nil {
-		log.Fatalf("error creating time entry source: %s\n", utils.GetErrorMessage(err))
+		t.payloadErr = err
+		return
 	}
+	t.payload = payload
+}
+
+func sync(context *cli.Context, location *time.Location, from time.Time, till time.Time, bail bool, dry bool, prune bool, parallel int, output string) int {
+	started := time.Now()
 
-	target, err := entries.NewTimeEntryTarget(context.Config.Target)
+	source, err := entries.NewTimeEntrySources(context.Config.GetSources())
 	if err != nil {
-		log.Fatalf("error creating time entry target: %s\n", utils.GetErrorMessage(err))
+		fatalf(context, "error creating time entry source: %s\n", utils.GetErrorMessage(err))
 	}
 
-	location, err := time.LoadLocation(utils.Coalesce(context.Config.TimeZone, "Local"))
+	cache, err := context.Config.GetCache()
 	if err != nil {
-		log.Fatalf("error creating timezone: %s\n", utils.GetErrorMessage(err))
+		fatalf(context, "error opening cache: %s\n", utils.GetErrorMessage(err))
 	}
+	// Exiting on an error skips the deferred calls, the lookups done so far are saved then too
+	atExit = append(atExit, func() { saveCache(cache) })
+	defer saveCache(cache)
 
-	entries, err := source.PullTimeEntries(from, till)
+	targets, err := entries.NewTimeEntryTargets(context.Config.GetTargets(), location, cache)
 	if err != nil {
-		log.Fatalf("error pulling time entries: %s\n", utils.GetErrorMessage(err))
+		fatalf(context, "error creating time entry target: %s\n", utils.GetErrorMessage(err))
 	}
 
-	for _, entry := range entries {
-		if dry {
-			lipgloss.Printf("%s %s\n", status.Render("○"), entry.String(location))
-			continue
-		}
+	transforms, err := entries.NewTimeEntryTransforms(context.Config.Transforms, location)
+	if err != nil {
+		fatalf(context, "error creating time entry transform: %s\n", utils.GetErrorMessage(err))
+	}
 
-		lipgloss.Printf("%s %s", status.Render("◌"), entry.String(location))
+	ledger_, err := ledger.Open(utils.Coalesce(context.Config.Ledger, cfg.GetDefaultLedgerPath()))
+	if err != nil {
+		fatalf(context, "error opening ledger: %s\n", utils.GetErrorMessage(err))
+	}
 
-		if err := target.PushTimeEntry(entry); err != nil {
-			lipgloss.Printf("\r%s %s\n", danger.Render("⏺"), entry.String(location))
-			lipgloss.Printf("╰─ %s\n\n", danger.Render(utils.GetErrorMessage(err)))
+	entries_, err := source.PullTimeEntries(context.Ctx, from.Add(-transforms.Lookback()), till)
+	if err != nil {
+		fatalf(context, "error pulling time entries: %s\n", utils.GetErrorMessage(err))
+	}
+
+	entries_, err = transforms.TransformTimeEntries(entries_)
+	if err != nil {
+		fatalf(context, "error transforming time entries: %s\n", utils.GetErrorMessage(err))
+	}
+
+	// Entries (or their pieces) belong to the period their start falls into
+	entries_ = arrays.Filter(entries_, func(entry entries.TimeEntry) bool { return entry.StartsWithin(from, till) })
+
+	// Machine readable output must stay parsable, so warnings are written to stderr
+	warnings := os.Stdout
+	if output != "text" {
+		warnings = os.Stderr
+	}
+
+	for _, overlap := range entries.FindOverlaps(entries_) {
+		lipgloss.Fprintf(warnings, "%s %s %s\n", warning.Render("⏺"), overlap[0].String(location), secondary.Render("("+overlap[0].Source+")"))
+		lipgloss.Fprintf(warnings, "╰─ %s\n\n", warning.Render(fmt.Sprintf("overlaps %s from %s", overlap[1].String(location), overlap[1].Source)))
+	}
+
+	tasks := plan(entries_, targets, ledger_, from, till, prune)
 
-			if bail {
-				os.Exit(1)
+	if !dry {
+		execute(context.Ctx, tasks, ledger_, parallel, bail)
+	}
+
+	// Progress is only shown on terminals, redirected output gets the final lines only
+	interactive := output == "text" && term.IsTerminal(os.Stdout.Fd())
+
+	wait := func(task *task) {
+		if interactive {
+			lipgloss.Printf("%s %s", status.Render("◌"), task.label(location))
+			defer lipgloss.Print("\r" + ansi.EraseEntireLine)
+		}
+		<-task.done
+	}
+
+	var results []syncResult
+
+	bailed := false
+	report := func(task *task, result syncResult) {
+		switch output {
+		case "json":
+			results = append(results, result)
+		case "ndjson":
+			printJson(result, false)
+		default:
+			// Nothing but the entries that were actually attempted is reported after bailing
+			if !bailed || (task.apply != nil && !task.skipped) {
+				printResult(task, result, location)
 			}
-		} else {
-			lipgloss.Printf("\r%s %s\n", success.Render("⏺"), entry.String(location))
 		}
+
+		bailed = bailed || (bail && result.Status == "failed")
+	}
+
+	summary := collect(context.Ctx, tasks, dry, output != "text", wait, report)
+	summary.Elapsed = time.Since(started).Seconds()
+
+	switch output {
+	case "json":
+		printJson(syncOutput{Results: results, Summary: summary}, true)
+	case "ndjson":
+		printJson(summary, false)
+	default:
+		style := success
+		if summary.ExitCode != constants.EXIT_OK {
+			style = danger
+		}
+		lipgloss.Printf("\n%s %s\n", style.Render("⏺"), summary.String())
+	}
+
+	return summary.ExitCode
+}
+
+func (t *task) label(location *time.Location) string {
+	label := t.entry.String(location) + " " + secondary.Render("→ "+t.target.Name)
+	if t.action == "updated" || t.action == "deleted" {
+		label += " " + secondary.Render("("+t.action+")")
+	}
+	return label
+}
+
+func printResult(task *task, result syncResult, location *time.Location) {
+	label := task.label(location)
+
+	switch result.Status {
+	case "unchanged":
+		lipgloss.Printf("%s %s\n", secondary.Render("⏺"), secondary.Render(task.entry.String(location)+" → "+task.target.Name))
+	case "removed":
+		lipgloss.Printf("%s %s %s\n", secondary.Render("⏺"), secondary.Render(task.entry.String(location)+" → "+task.target.Name), secondary.Render("(removed from source, use --prune to delete)"))
+	case "dry":
+		lipgloss.Printf("%s %s\n", status.Render("○"), label)
+	case "skipped":
+	case "cancelled":
+		lipgloss.Printf("%s %s %s\n", secondary.Render("⏺"), label, secondary.Render("(cancelled)"))
+	case "failed":
+		lipgloss.Printf("%s %s\n", danger.Render("⏺"), label)
+		lipgloss.Printf("╰─ %s\n\n", danger.Render(result.Error))
+	default:
+		lipgloss.Printf("%s %s\n", success.Render("⏺"), label)
+	}
+}
+
+// atExit holds what is still to be done when exiting on an error, as exiting skips the deferred calls.
+var atExit []func()
+
+// fatalf reports the error stopping the command and exits, with EXIT_INTERRUPTED if the command was
+// interrupted (e.g. aborting the pull in flight) and with EXIT_ERROR otherwise.
+func fatalf(context *cli.Context, format string, v ...any) {
+	log.Printf(format, v...)
+
+	for _, f := range slices.Backward(atExit) {
+		f()
+	}
+
+	if context.Ctx.Err() != nil {
+		os.Exit(constants.EXIT_INTERRUPTED)
 	}
+	os.Exit(constants.EXIT_ERROR)
+}
+
+// saveCache saves the issues looked up, failing only costs looking them up again so it is just a warning.
+func saveCache(cache *cache.Cache) {
+	if err := cache.Save(); err != nil {
+		lipgloss.Fprintf(os.Stderr, "%s %s\n", warning.Render("⏺"), fmt.Sprintf("error saving cache: %s", utils.GetErrorMessage(err)))
+	}
+}
+
+// getLocation returns the configured timezone, the days of the period (e.g. the dates given by --from and
+// --till) are taken in it, like the days entries are split and grouped by.
+func getLocation(context *cli.Context) *time.Location {
+	location, err := time.LoadLocation(utils.Coalesce(context.Config.TimeZone, "Local"))
+	if err != nil {
+		fatalf(context, "error creating timezone: %s\n", utils.GetErrorMessage(err))
+	}
+	return location
+}
+
+// today returns the start of the current day in the location.
+func today(location *time.Location) time.Time {
+	now := constants.NOW.In(location)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+}
+
+func printJson(value any, indent bool) {
+	var content []byte
+	var err error
+	if indent {
+		content, err = json.MarshalIndent(value, "", "  ")
+	} else {
+		content, err = json.Marshal(value)
+	}
+	if err != nil {
+		log.Fatalf("error encoding output: %s\n", utils.GetErrorMessage(err))
+	}
+
+	os.Stdout.Write(append(content, '\n'))
 }
 
 func Sync(args []string, context *cli.Context) {
-	command := &cli.FlagSet{FlagSet: flag.NewFlagSet("sync", flag.ExitOnError)}
+	command := &cli.FlagSet{FlagSet: flag.NewFlagSet("sync", flag.ContinueOnError)}
+
+	location := getLocation(context)
 
-	fromFlag := command.Time("from", constants.TODAY, "date/datetime to sync work logs from (inclusive)")
-	tillFlag := command.Time("till", constants.TOMORROW, "date/datetime to sync work logs till (exclusive)")
+	fromFlag := command.Time("from", today(location), "date/datetime to sync work logs from (inclusive)")
+	tillFlag := command.Time("till", today(location).AddDate(0, 0, 1), "date/datetime to sync work logs till (exclusive)")
 
 	bailFlag := command.Bool("bail", false, "stop the synchronization process on the first error encountered")
 	dryFlag := command.Bool("dry", false, "perform a dry run without making any changes")
+	pruneFlag := command.Bool("prune", false, "delete worklogs whose entries were removed from the source")
+	parallelFlag := command.Int("parallel", 1, "number of entries synchronized concurrently")
+	outputFlag := command.String("output", "text", "output format: text, json or ndjson")
 
 	command.Usage = func() {
 		fmt.Printf(`Usage: timesheets sync [options]
 
 Synchronize your work logs.
 
+Entries that were already pushed are recorded in a local ledger and skipped on
+subsequent runs, so synchronizing the same period repeatedly is safe. Entries
+changed since their last synchronization are updated in place, entries removed
+from the source are only deleted when --prune is given. Entries are synchronized
+with the period their start falls into, so an entry (or a piece of a split entry)
+starting before --from is left to the synchronization of the previous period.
+The dates of --from and --till are days in the configured timezone.
+
+With --parallel the entries are synchronized concurrently, but still reported
+in their original order. Combined with --bail, no further entries are started
+after the first error, while the requests in flight are completed and recorded.
+
+The exit code is 0 when every entry was synchronized, 1 when nothing could be
+synchronized (e.g. invalid configuration or unreachable source), 2 when some
+of the entries failed and 130 when interrupted.
+
+With --output json or ndjson every entry is reported with its target, the
+payload sent (or to be sent, as payloadError if it cannot be prepared), the
+status and the error (if any), followed by a summary of the statuses. Removed
+and deleted worklogs have no payload, as nothing is sent for them. Styling and
+progress are left out whenever stdout is not a terminal.
+
 Options:
 
 `)
@@ -90,10 +591,10 @@ For more information, visit: https://github.com/tornermarton/timesheets
 	}
 
 	command.Parse(args)
-	if command.NArg() > 0 {
+	if command.NArg() > 0 || *parallelFlag < 1 || !slices.Contains([]string{"text", "json", "ndjson"}, *outputFlag) {
 		command.Usage()
-		os.Exit(1)
+		os.Exit(constants.EXIT_ERROR)
 	}
 
-	sync(context, *fromFlag, *tillFlag, *bailFlag, *dryFlag)
+	os.Exit(sync(context, location, *fromFlag, *tillFlag, *bailFlag, *dryFlag, *pruneFlag, *parallelFlag, *outputFlag))
 }
@@ -0,0 +1,463 @@
+package cmd
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	stdsync "sync"
+	"testing"
+	"time"
+
+	"github.com/tornermarton/timesheets/internal/constants"
+	"github.com/tornermarton/timesheets/internal/entries"
+	"github.com/tornermarton/timesheets/internal/ledger"
+)
+
+// fakeTarget records the requests it receives, failing for the entries (or worklogs) in fail and the previews
+// of the entries in fail as "preview <entry>". The requests of
+// the entries (or worklogs) in block are announced on started and wait for their channel to be closed.
+type fakeTarget struct {
+	fail    map[string]bool
+	block   map[string]chan struct{}
+	started chan string
+
+	mutex    stdsync.Mutex
+	requests []string
+}
+
+func (f *fakeTarget) request(request string, key string) error {
+	if block, ok := f.block[key]; ok {
+		f.started <- key
+		<-block
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.requests = append(f.requests, request)
+	if f.fail[key] {
+		return fmt.Errorf("cannot %s", request)
+	}
+	return nil
+}
+
+func (f *fakeTarget) PushTimeEntry(ctx stdcontext.Context, entry entries.TimeEntry) (string, error) {
+	if err := f.request("push "+entry.Id, entry.Id); err != nil {
+		return "", err
+	}
+	return "w-" + entry.Id, nil
+}
+
+func (f *fakeTarget) UpdateTimeEntry(ctx stdcontext.Context, worklog string, entry entries.TimeEntry) (string, error) {
+	return worklog, f.request("update "+worklog, entry.Id)
+}
+
+func (f *fakeTarget) DeleteTimeEntry(ctx stdcontext.Context, worklog string) error {
+	return f.request("delete "+worklog, worklog)
+}
+
+func (f *fakeTarget) ListTimeEntries(ctx stdcontext.Context, from time.Time, till time.Time) ([]entries.TimeEntry, error) {
+	return nil, nil
+}
+
+func (f *fakeTarget) MapTimeEntry(entry entries.TimeEntry) entries.TimeEntry {
+	return entry
+}
+
+func (f *fakeTarget) PreviewTimeEntry(ctx stdcontext.Context, entry entries.TimeEntry) (any, error) {
+	if f.fail["preview "+entry.Id] {
+		return struct{}{}, fmt.Errorf("cannot resolve issue %s", entry.Issue)
+	}
+	return map[string]any{"issue": entry.Issue}, nil
+}
+
+// fakeLedger keeps the records in memory, failing to save when asked to.
+type fakeLedger struct {
+	fail bool
+
+	mutex   stdsync.Mutex
+	records map[string]ledger.Record
+	saved   int
+}
+
+func newFakeLedger(records ...ledger.Record) *fakeLedger {
+	ledger_ := &fakeLedger{records: map[string]ledger.Record{}}
+	for _, record := range records {
+		ledger_.Put(record)
+	}
+	return ledger_
+}
+
+func (f *fakeLedger) Get(target string, entry string) (ledger.Record, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	record, ok := f.records[target+"/"+entry]
+	return record, ok
+}
+
+func (f *fakeLedger) Put(record ledger.Record) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.records[record.Target+"/"+record.Entry] = record
+}
+
+func (f *fakeLedger) Delete(target string, entry string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	delete(f.records, target+"/"+entry)
+}
+
+func (f *fakeLedger) Records(target string, from time.Time, till time.Time) []ledger.Record {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var records []ledger.Record
+	for _, record := range f.records {
+		if record.Target == target && !record.From.Before(from) && record.From.Before(till) {
+			records = append(records, record)
+		}
+	}
+	slices.SortFunc(records, func(a ledger.Record, b ledger.Record) int { return a.From.Compare(b.From) })
+	return records
+}
+
+func (f *fakeLedger) Save() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.fail {
+		return fmt.Errorf("disk full")
+	}
+	f.saved++
+	return nil
+}
+
+var monday = time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+
+func newEntry(id string, hour int) entries.TimeEntry {
+	from := monday.Add(time.Duration(hour) * time.Hour)
+	return entries.TimeEntry{Id: id, Issue: "ABC-" + id, From: from, Till: from.Add(30 * time.Minute), Description: "Work"}
+}
+
+// planned lists the tasks as target/entry:action
+func planned(tasks []*task) []string {
+	var out []string
+	for _, task := range tasks {
+		out = append(out, task.target.Name+"/"+task.entry.Id+":"+task.action)
+	}
+	return out
+}
+
+func TestPlan(t *testing.T) {
+	kronos := entries.NamedTimeEntryTarget{TimeEntryTarget: &fakeTarget{}, Name: "Kronos"}
+	meetings := entries.NamedTimeEntryTarget{
+		TimeEntryTarget: &fakeTarget{},
+		Name:            "Meetings",
+		Filter:          entries.TimeEntryFilter{Include: entries.TimeEntryMatcher{Issues: []string{"ABC-2"}}},
+	}
+
+	changed := newEntry("2", 10)
+	changed.Description = "Review"
+
+	tests := []struct {
+		name     string
+		records  []ledger.Record
+		entries  []entries.TimeEntry
+		prune    bool
+		expected []string
+	}{
+		{
+			name:     "created",
+			entries:  []entries.TimeEntry{newEntry("1", 9), newEntry("2", 10)},
+			expected: []string{"Kronos/1:created", "Kronos/2:created", "Meetings/2:created"},
+		},
+		{
+			name:     "unchanged and updated",
+			records:  []ledger.Record{record("Kronos", "w-1", newEntry("1", 9)), record("Kronos", "w-2", newEntry("2", 10)), record("Meetings", "w-2", newEntry("2", 10))},
+			entries:  []entries.TimeEntry{newEntry("1", 9), changed},
+			expected: []string{"Kronos/1:unchanged", "Kronos/2:updated", "Meetings/2:updated"},
+		},
+		{
+			name:     "removed",
+			records:  []ledger.Record{record("Kronos", "w-1", newEntry("1", 9)), record("Kronos", "w-2", newEntry("2", 10))},
+			entries:  []entries.TimeEntry{newEntry("2", 10)},
+			expected: []string{"Kronos/2:unchanged", "Meetings/2:created", "Kronos/1:removed"},
+		},
+		{
+			name:     "deleted with prune",
+			records:  []ledger.Record{record("Kronos", "w-1", newEntry("1", 9)), record("Meetings", "w-2", newEntry("2", 10))},
+			entries:  []entries.TimeEntry{newEntry("3", 11)},
+			prune:    true,
+			expected: []string{"Kronos/3:created", "Kronos/1:deleted", "Meetings/2:deleted"},
+		},
+		{
+			name:     "no longer routed to the target",
+			records:  []ledger.Record{record("Meetings", "w-1", newEntry("1", 9))},
+			entries:  []entries.TimeEntry{newEntry("1", 9)},
+			prune:    true,
+			expected: []string{"Kronos/1:created", "Meetings/1:deleted"},
+		},
+		{
+			name:     "records outside of the period",
+			records:  []ledger.Record{record("Kronos", "w-1", newEntry("1", -2)), record("Kronos", "w-2", newEntry("2", 24))},
+			prune:    true,
+			expected: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tasks := plan(test.entries, []entries.NamedTimeEntryTarget{kronos, meetings}, newFakeLedger(test.records...), monday, monday.Add(24*time.Hour), test.prune)
+			if actual := planned(tasks); !slices.Equal(actual, test.expected) {
+				t.Errorf("got %v, want %v", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestExecuteKeepsOrder(t *testing.T) {
+	one, two := make(chan struct{}), make(chan struct{})
+	target := &fakeTarget{block: map[string]chan struct{}{"1": one, "2": two}, started: make(chan string, 2)}
+	ledger_ := newFakeLedger()
+	targets := []entries.NamedTimeEntryTarget{{TimeEntryTarget: target, Name: "Kronos"}}
+
+	tasks := plan([]entries.TimeEntry{newEntry("1", 9), newEntry("2", 10), newEntry("3", 11)}, targets, ledger_, monday, monday.Add(24*time.Hour), false)
+	execute(stdcontext.Background(), tasks, ledger_, 3, false)
+
+	var statuses []string
+	summary := collect(stdcontext.Background(), tasks, false, false, func(task *task) {
+		// The later entries complete first
+		<-tasks[2].done
+		close(two)
+		<-tasks[1].done
+		close(one)
+		<-task.done
+	}, func(task *task, result syncResult) {
+		statuses = append(statuses, result.Entry.Id+":"+result.Status)
+	})
+
+	if expected := []string{"1:created", "2:created", "3:created"}; !slices.Equal(statuses, expected) {
+		t.Errorf("expected the entries to be reported in their order, got %v", statuses)
+	}
+	if expected := []string{"push 3", "push 2", "push 1"}; !slices.Equal(target.requests, expected) {
+		t.Errorf("expected the entries to be pushed concurrently, got %v", target.requests)
+	}
+	if summary.Counts["created"] != 3 || len(ledger_.records) != 3 {
+		t.Errorf("expected every entry to be recorded, got %v and %v", summary.Counts, ledger_.records)
+	}
+}
+
+func TestExecuteBail(t *testing.T) {
+	one, two := make(chan struct{}), make(chan struct{})
+
+	// The first entry fails while the second one is in flight, which is still completed
+	target := &fakeTarget{fail: map[string]bool{"1": true}, block: map[string]chan struct{}{"1": one, "2": two}, started: make(chan string, 2)}
+	ledger_ := newFakeLedger()
+	targets := []entries.NamedTimeEntryTarget{{TimeEntryTarget: target, Name: "Kronos"}}
+
+	tasks := plan([]entries.TimeEntry{newEntry("1", 9), newEntry("2", 10), newEntry("3", 11)}, targets, ledger_, monday, monday.Add(24*time.Hour), false)
+	execute(stdcontext.Background(), tasks, ledger_, 2, true)
+
+	<-target.started
+	<-target.started
+	close(one)
+	<-tasks[0].done
+	<-tasks[2].done
+	close(two)
+	<-tasks[1].done
+
+	var statuses []string
+	for _, task := range tasks {
+		statuses = append(statuses, task.result(false).Status)
+	}
+
+	if expected := []string{"failed", "created", "skipped"}; !slices.Equal(statuses, expected) {
+		t.Errorf("expected only the tasks not started yet to be skipped, got %v", statuses)
+	}
+	if _, ok := ledger_.Get("Kronos", "2"); !ok || len(ledger_.records) != 1 {
+		t.Errorf("expected the task in flight to be recorded, got %v", ledger_.records)
+	}
+	if strings.Join(target.requests, ",") != "push 1,push 2" {
+		t.Errorf("expected the skipped entry not to be pushed, got %v", target.requests)
+	}
+}
+
+// synchronize plans and applies the tasks for the entries as sync does, returning the summary and the result
+// of every task in order.
+func synchronize(target *fakeTarget, ledger_ records, entries_ []entries.TimeEntry, prune bool) (syncSummary, []syncResult) {
+	ctx := stdcontext.Background()
+	targets := []entries.NamedTimeEntryTarget{{TimeEntryTarget: target, Name: "Kronos"}}
+
+	tasks := plan(entries_, targets, ledger_, monday, monday.Add(24*time.Hour), prune)
+	execute(ctx, tasks, ledger_, 1, false)
+
+	var results []syncResult
+	summary := collect(ctx, tasks, false, false, func(task *task) { <-task.done }, func(task *task, result syncResult) {
+		results = append(results, result)
+	})
+	return summary, results
+}
+
+func TestSyncTwice(t *testing.T) {
+	ledger_ := newFakeLedger()
+	entries_ := []entries.TimeEntry{newEntry("1", 9), newEntry("2", 10)}
+
+	synchronize(&fakeTarget{}, ledger_, entries_, true)
+
+	target := &fakeTarget{}
+	summary, _ := synchronize(target, ledger_, entries_, true)
+
+	if len(target.requests) != 0 || summary.Counts["unchanged"] != 2 {
+		t.Errorf("expected the recorded entries to be left alone, got %v (%v)", target.requests, summary.Counts)
+	}
+}
+
+func TestSyncChanged(t *testing.T) {
+	ledger_ := newFakeLedger(record("Kronos", "w-1", newEntry("1", 9)), record("Kronos", "w-2", newEntry("2", 10)))
+
+	changed := newEntry("1", 9)
+	changed.Description = "Review"
+
+	target := &fakeTarget{}
+	_, results := synchronize(target, ledger_, []entries.TimeEntry{changed}, true)
+
+	if expected := []string{"update w-1", "delete w-2"}; !slices.Equal(target.requests, expected) {
+		t.Errorf("expected the recorded worklogs to be updated and deleted, got %v", target.requests)
+	}
+	if results[0].Status != "updated" || results[0].Worklog != "w-1" || results[1].Status != "deleted" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	if record, _ := ledger_.Get("Kronos", "1"); record.Checksum != changed.Checksum() || len(ledger_.records) != 1 || ledger_.saved != 2 {
+		t.Errorf("expected the changes to be recorded, got %v", ledger_.records)
+	}
+}
+
+func TestSyncFailed(t *testing.T) {
+	existing := []ledger.Record{record("Kronos", "w-1", newEntry("1", 9)), record("Kronos", "w-2", newEntry("2", 10))}
+	ledger_ := newFakeLedger(existing...)
+
+	changed := newEntry("1", 9)
+	changed.Description = "Review"
+
+	target := &fakeTarget{fail: map[string]bool{"1": true, "w-2": true, "3": true}}
+	summary, _ := synchronize(target, ledger_, []entries.TimeEntry{changed, newEntry("3", 11)}, true)
+
+	if summary.Counts["failed"] != 3 || ledger_.saved != 0 {
+		t.Errorf("expected every change to fail, got %v", summary.Counts)
+	}
+	for _, record := range existing {
+		if actual, _ := ledger_.Get(record.Target, record.Entry); actual != record {
+			t.Errorf("expected the record to be left unchanged, got %+v", actual)
+		}
+	}
+	if _, ok := ledger_.Get("Kronos", "3"); ok {
+		t.Errorf("expected the entry failed to be created not to be recorded")
+	}
+
+	// A change applied but not saved is reported, as the worklog would be created again by the next run
+	ledger_.fail = true
+	_, results := synchronize(&fakeTarget{}, ledger_, []entries.TimeEntry{newEntry("3", 11)}, false)
+	if results[0].Status != "failed" || results[0].Error != "worklog w-3 was created but could not be recorded: disk full" {
+		t.Errorf("expected the failure to record the worklog, got %+v", results[0])
+	}
+}
+
+func TestSyncExitCode(t *testing.T) {
+	interrupted, cancel := stdcontext.WithCancel(stdcontext.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		ctx      stdcontext.Context
+		fail     map[string]bool
+		expected int
+	}{
+		{name: "ok", ctx: stdcontext.Background(), expected: constants.EXIT_OK},
+		{name: "partial", ctx: stdcontext.Background(), fail: map[string]bool{"2": true}, expected: constants.EXIT_PARTIAL},
+		{name: "interrupted", ctx: interrupted, expected: constants.EXIT_INTERRUPTED},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ledger_ := newFakeLedger()
+			targets := []entries.NamedTimeEntryTarget{{TimeEntryTarget: &fakeTarget{fail: test.fail}, Name: "Kronos"}}
+
+			tasks := plan([]entries.TimeEntry{newEntry("1", 9), newEntry("2", 10)}, targets, ledger_, monday, monday.Add(24*time.Hour), false)
+			execute(test.ctx, tasks, ledger_, 1, false)
+			summary := collect(test.ctx, tasks, false, false, func(task *task) { <-task.done }, func(task *task, result syncResult) {})
+
+			if summary.ExitCode != test.expected {
+				t.Errorf("got %d, want %d (%v)", summary.ExitCode, test.expected, summary.Counts)
+			}
+		})
+	}
+}
+
+func TestSyncOutput(t *testing.T) {
+	ctx := stdcontext.Background()
+	ledger_ := newFakeLedger(record("Kronos", "w-1", newEntry("1", 9)), record("Kronos", "w-4", newEntry("4", 12)))
+	targets := []entries.NamedTimeEntryTarget{{TimeEntryTarget: &fakeTarget{fail: map[string]bool{"preview 3": true}}, Name: "Kronos"}}
+
+	tasks := plan([]entries.TimeEntry{newEntry("1", 9), newEntry("2", 10), newEntry("3", 11)}, targets, ledger_, monday, monday.Add(24*time.Hour), false)
+	execute(ctx, tasks, ledger_, 1, false)
+
+	var results []syncResult
+	summary := collect(ctx, tasks, false, true, func(task *task) { <-task.done }, func(task *task, result syncResult) {
+		results = append(results, result)
+	})
+
+	content, err := json.Marshal(syncOutput{Results: results, Summary: summary})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var output struct {
+		Results []map[string]any `json:"results"`
+		Summary map[string]any   `json:"summary"`
+	}
+	if err := json.Unmarshal(content, &output); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	keys := func(object map[string]any) string {
+		return strings.Join(slices.Sorted(maps.Keys(object)), ",")
+	}
+
+	// Every entry has a payload or the error preparing it, except for the removed one
+	expected := []string{
+		"entry,payload,status,target,type,worklog",
+		"action,entry,payload,status,target,type,worklog",
+		"action,entry,payloadError,status,target,type,worklog",
+		"entry,status,target,type,worklog",
+	}
+	for i, result := range output.Results {
+		if keys(result) != expected[i] {
+			t.Errorf("result %d: got %s, want %s", i, keys(result), expected[i])
+		}
+	}
+	if payload := output.Results[0]["payload"]; !reflect.DeepEqual(payload, map[string]any{"issue": "ABC-1"}) {
+		t.Errorf("expected the payload of the unchanged entry, got %v", payload)
+	}
+	if output.Results[2]["payloadError"] != "cannot resolve issue ABC-3" || output.Results[2]["status"] != "created" {
+		t.Errorf("expected the error of the payload next to the status, got %v", output.Results[2])
+	}
+
+	if keys(output.Summary) != "counts,dry,durations,elapsed,exitCode,type" || output.Summary["type"] != "summary" {
+		t.Errorf("unexpected summary: %v", output.Summary)
+	}
+
+	// Lines of ndjson are the results and the summary on their own, told apart by their type
+	for _, value := range []any{results[0], summary} {
+		line, _ := json.Marshal(value)
+		if strings.Contains(string(line), "\n") || !strings.HasPrefix(string(line), `{"type":`) {
+			t.Errorf("expected a line starting with the type, got %s", line)
+		}
+	}
+}
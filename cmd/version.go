@@ -9,6 +9,7 @@ import (
 	"runtime/debug"
 
 	"github.com/tornermarton/timesheets/internal/cli"
+	"github.com/tornermarton/timesheets/internal/constants"
 )
 
 func getBuildInfo() *debug.BuildInfo {
@@ -89,7 +90,7 @@ For more information, see https://github.com/tornermarton/timesheets
 }
 
 func Version(args []string, context *cli.Context) {
-	command := &cli.FlagSet{FlagSet: flag.NewFlagSet("version", flag.ExitOnError)}
+	command := &cli.FlagSet{FlagSet: flag.NewFlagSet("version", flag.ContinueOnError)}
 
 	command.Usage = func() {
 		fmt.Printf(`Usage: timesheets version
@@ -103,7 +104,7 @@ For more information, visit: https://github.com/tornermarton/timesheets
 	command.Parse(args)
 	if command.NArg() > 0 {
 		command.Usage()
-		os.Exit(1)
+		os.Exit(constants.EXIT_ERROR)
 	}
 
 	version(context)
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type item struct {
+	Value   json.RawMessage `json:"value"`
+	Expires time.Time       `json:"expires"`
+}
+
+// Cache remembers values across runs, every value expires after the TTL. A nil cache remembers nothing,
+// so it can be passed around when caching is disabled.
+type Cache struct {
+	path string
+	ttl  time.Duration
+
+	mutex sync.Mutex
+	items map[string]item
+}
+
+type cacheFile struct {
+	Items map[string]item `json:"items"`
+}
+
+func Open(path string, ttl time.Duration) (*Cache, error) {
+	cache := &Cache{
+		path:  path,
+		ttl:   ttl,
+		items: map[string]item{},
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file cacheFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for key, item := range file.Items {
+		if item.Expires.After(now) {
+			cache.items[key] = item
+		}
+	}
+
+	return cache, nil
+}
+
+// Get decodes the value of the key into out, reporting whether an unexpired value was found.
+func (c *Cache) Get(key string, out any) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mutex.Lock()
+	item, ok := c.items[key]
+	c.mutex.Unlock()
+
+	if !ok || !item.Expires.After(time.Now()) {
+		return false
+	}
+
+	return json.Unmarshal(item.Value, out) == nil
+}
+
+func (c *Cache) Put(key string, value any) {
+	if c == nil {
+		return
+	}
+
+	content, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	c.mutex.Lock()
+	c.items[key] = item{Value: content, Expires: time.Now().Add(c.ttl)}
+	c.mutex.Unlock()
+}
+
+func (c *Cache) Save() error {
+	if c == nil {
+		return nil
+	}
+
+	c.mutex.Lock()
+	content, err := json.MarshalIndent(cacheFile{Items: c.items}, "", "  ")
+	c.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so an interrupted run never leaves a truncated cache behind
+	temp := c.path + ".tmp"
+	if err := os.WriteFile(temp, content, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(temp, c.path)
+}
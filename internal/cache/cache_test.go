@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "cache.json")
+
+	cache, err := Open(path, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	cache.Put("issue/ABC-1", map[string]string{"id": "10042"})
+	if err := cache.Save(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	reopened, err := Open(path, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var value map[string]string
+	if !reopened.Get("issue/ABC-1", &value) || value["id"] != "10042" {
+		t.Errorf("expected cached value, got %v", value)
+	}
+	if reopened.Get("issue/ABC-2", &value) {
+		t.Errorf("expected missing key not to be found")
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	cache, _ := Open(path, -time.Second)
+	cache.Put("issue/ABC-1", true)
+	cache.Save()
+
+	var value bool
+	if cache.Get("issue/ABC-1", &value) {
+		t.Errorf("expected expired value not to be found")
+	}
+
+	reopened, _ := Open(path, time.Hour)
+	if len(reopened.items) != 0 {
+		t.Errorf("expected expired values to be dropped, got %v", reopened.items)
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var cache *Cache
+
+	cache.Put("issue/ABC-1", true)
+
+	var value bool
+	if cache.Get("issue/ABC-1", &value) {
+		t.Errorf("expected nil cache to remember nothing")
+	}
+	if err := cache.Save(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
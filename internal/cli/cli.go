@@ -1,10 +1,16 @@
 package cli
 
 import (
+	"context"
+
 	"github.com/tornermarton/timesheets/internal/config"
 )
 
 type Context struct {
 	Version string
 	Config  *config.Config
+	// ConfigPath is where the configuration is read from (and written to by config init)
+	ConfigPath string
+	// Ctx is cancelled on interrupt, aborting the pending requests
+	Ctx context.Context
 }
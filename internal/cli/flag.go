@@ -1,16 +1,35 @@
 package cli
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"os"
 	"time"
+
+	"github.com/tornermarton/timesheets/internal/constants"
 )
 
 type FlagSet struct {
 	*flag.FlagSet
 }
 
-// -- time.Time Value
+// Parse parses the arguments of a flag set created with flag.ContinueOnError, exiting with EXIT_ERROR on
+// invalid ones instead of the 2 of flag.ExitOnError (which is EXIT_PARTIAL, a partially failed sync).
+func (f *FlagSet) Parse(arguments []string) error {
+	err := f.FlagSet.Parse(arguments)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(constants.EXIT_OK)
+	case err != nil:
+		os.Exit(constants.EXIT_ERROR)
+	}
+
+	return nil
+}
+
+// -- time.Time Value, dates are parsed as midnight in the location of the default value (e.g. the configured
+// timezone), so the days of a period match the days entries are split and grouped by
 type timeValue time.Time
 
 func newTimeValue(val time.Time, p *time.Time) *timeValue {
@@ -25,7 +44,7 @@ func (t *timeValue) Set(s string) error {
 	}
 
 	for _, layout := range layouts {
-		if v, err := time.Parse(layout, s); err == nil {
+		if v, err := time.ParseInLocation(layout, s, time.Time(*t).Location()); err == nil {
 			*t = timeValue(v)
 			return nil
 		}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/tornermarton/timesheets/internal/arrays"
+	"github.com/tornermarton/timesheets/internal/entries"
+)
+
+func TestTimeInLocation(t *testing.T) {
+	location, _ := time.LoadLocation("Europe/Budapest")
+
+	command := &FlagSet{FlagSet: flag.NewFlagSet("test", flag.ContinueOnError)}
+	from := command.Time("from", time.Date(2025, 6, 1, 0, 0, 0, 0, location), "")
+	till := command.Time("till", time.Date(2025, 6, 2, 0, 0, 0, 0, location), "")
+	at := command.Time("at", time.Date(2025, 6, 1, 0, 0, 0, 0, location), "")
+
+	command.Parse([]string{"--from", "2025-06-02", "--till", "2025-06-03", "--at", "2025-06-02T12:00:00Z"})
+
+	if !from.Equal(time.Date(2025, 6, 2, 0, 0, 0, 0, location)) || !till.Equal(time.Date(2025, 6, 3, 0, 0, 0, 0, location)) {
+		t.Errorf("expected the dates to be midnight in the location, got %s and %s", from, till)
+	}
+	if !at.Equal(time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the datetime to keep its offset, got %s", at)
+	}
+
+	// The piece after local midnight starts on 2025-06-01 in UTC, but belongs to the period of 2025-06-02
+	split := entries.Split{Location: location}
+	pieces, _ := split.TransformTimeEntries([]entries.TimeEntry{
+		{Id: "1", From: time.Date(2025, 6, 1, 23, 0, 0, 0, location), Till: time.Date(2025, 6, 2, 1, 30, 0, 0, location)},
+		{Id: "2", From: time.Date(2025, 6, 2, 23, 30, 0, 0, location), Till: time.Date(2025, 6, 3, 0, 30, 0, 0, location)},
+	})
+	within := arrays.Filter(pieces, func(entry entries.TimeEntry) bool { return entry.StartsWithin(*from, *till) })
+
+	if len(within) != 2 || within[0].Id != "1@2025-06-02" || within[1].Id != "2" {
+		t.Errorf("expected the pieces starting on the local day, got %+v", within)
+	}
+}
@@ -1,19 +1,189 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/tornermarton/timesheets/internal/arrays"
+	"github.com/tornermarton/timesheets/internal/cache"
 	"github.com/tornermarton/timesheets/internal/entries"
+	"github.com/tornermarton/timesheets/internal/specs"
+	"github.com/tornermarton/timesheets/internal/utils"
 )
 
+type CacheConfig struct {
+	Path *string `yaml:"path,omitempty"`
+	Ttl  string  `yaml:"ttl" schema:"required"`
+}
+
 type Config struct {
-	Source entries.TimeEntrySourceConfig `yaml:"source"`
-	Target entries.TimeEntryTargetConfig `yaml:"target"`
+	Source  *entries.TimeEntrySourceConfig  `yaml:"source,omitempty"`
+	Sources []entries.TimeEntrySourceConfig `yaml:"sources,omitempty"`
+
+	Target  *entries.TimeEntryTargetConfig  `yaml:"target,omitempty"`
+	Targets []entries.TimeEntryTargetConfig `yaml:"targets,omitempty"`
+
+	Transforms []entries.TimeEntryTransformConfig `yaml:"transforms,omitempty"`
 
 	TimeZone *string `yaml:"timezone"`
+	Ledger   *string `yaml:"ledger,omitempty"`
+
+	// Cache remembers issue lookups across runs, without it they are only remembered within a run
+	Cache *CacheConfig `yaml:"cache,omitempty"`
+
+	// node is the parsed file, kept to report problems with their lines
+	node *yaml.Node
+}
+
+// GetSources returns every configured source, the single "source" comes first.
+func (c *Config) GetSources() []entries.TimeEntrySourceConfig {
+	var sources []entries.TimeEntrySourceConfig
+	if c.Source != nil {
+		sources = append(sources, *c.Source)
+	}
+	return append(sources, c.Sources...)
+}
+
+// GetTargets returns every configured target, the single "target" comes first.
+func (c *Config) GetTargets() []entries.TimeEntryTargetConfig {
+	var targets []entries.TimeEntryTargetConfig
+	if c.Target != nil {
+		targets = append(targets, *c.Target)
+	}
+	return append(targets, c.Targets...)
+}
+
+// GetCache opens the configured cache, returning nil when caching across runs is disabled.
+func (c *Config) GetCache() (*cache.Cache, error) {
+	if c.Cache == nil {
+		return nil, nil
+	}
+
+	ttl, err := time.ParseDuration(c.Cache.Ttl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache ttl: %w", err)
+	}
+
+	return cache.Open(utils.Coalesce(c.Cache.Path, GetDefaultCachePath()), ttl)
+}
+
+// Resolve returns the effective configuration, with the defaults of every source, target and transform applied.
+func (c *Config) Resolve() (*Config, error) {
+	resolved := *c
+
+	timezone := utils.Coalesce(c.TimeZone, "Local")
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %w", err)
+	}
+	resolved.TimeZone = &timezone
+
+	ledger := utils.Coalesce(c.Ledger, GetDefaultLedgerPath())
+	resolved.Ledger = &ledger
+
+	if c.Cache != nil {
+		path := utils.Coalesce(c.Cache.Path, GetDefaultCachePath())
+		resolved.Cache = &CacheConfig{Path: &path, Ttl: c.Cache.Ttl}
+	}
+
+	if c.Source != nil {
+		source, err := entries.ResolveTimeEntrySourceConfig(*c.Source)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Source = &source
+	}
+
+	resolved.Sources, err = arrays.MapE(c.Sources, entries.ResolveTimeEntrySourceConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Target != nil {
+		target, err := entries.ResolveTimeEntryTargetConfig(*c.Target, location)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Target = &target
+	}
+
+	resolved.Targets, err = arrays.MapE(c.Targets, func(target entries.TimeEntryTargetConfig) (entries.TimeEntryTargetConfig, error) {
+		return entries.ResolveTimeEntryTargetConfig(target, location)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resolved.Transforms, err = arrays.MapE(c.Transforms, func(transform entries.TimeEntryTransformConfig) (entries.TimeEntryTransformConfig, error) {
+		return entries.ResolveTimeEntryTransformConfig(transform, location)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &resolved, nil
+}
+
+// Validate reports every problem of the configuration (e.g. unknown fields, values of the wrong type or
+// invalid specs) instead of only the first one, with the line they occur on where known.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.node != nil {
+		if err := specs.Decode(c.node, &Config{}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	location, err := time.LoadLocation(utils.Coalesce(c.TimeZone, "Local"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid timezone: %w", err))
+		location = time.Local
+	}
+
+	if c.Cache != nil {
+		if _, err := time.ParseDuration(c.Cache.Ttl); err != nil {
+			errs = append(errs, fmt.Errorf("invalid cache ttl: %w", err))
+		}
+	}
+
+	if len(c.GetSources()) == 0 {
+		errs = append(errs, fmt.Errorf("no time entry source configured"))
+	}
+	for _, source := range c.GetSources() {
+		if _, err := entries.NewTimeEntrySource(source); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(c.GetTargets()) == 0 {
+		errs = append(errs, fmt.Errorf("no time entry target configured"))
+	}
+	names := map[string]bool{}
+	for _, target := range c.GetTargets() {
+		if _, err := entries.NewTimeEntryTarget(target, location, nil); err != nil {
+			errs = append(errs, err)
+		}
+
+		name := utils.DefaultString(target.Name, target.Kind)
+		if names[name] {
+			errs = append(errs, fmt.Errorf("duplicate time entry target name: %s", name))
+		}
+		names[name] = true
+	}
+
+	for _, transform := range c.Transforms {
+		if _, err := entries.NewTimeEntryTransform(transform, location); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(specs.Sort(errs)...)
 }
 
 func GetDefaultPath() string {
@@ -32,23 +202,82 @@ func GetDefaultPath() string {
 	panic("Could not determine default config path")
 }
 
+func GetDefaultLedgerPath() string {
+	if state := os.Getenv("XDG_STATE_HOME"); state != "" {
+		return filepath.Join(state, "timesheets", "ledger.json")
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "state", "timesheets", "ledger.json")
+	}
+
+	if cwd, err := os.Getwd(); err == nil {
+		return filepath.Join(cwd, "ledger.json")
+	}
+
+	panic("Could not determine default ledger path")
+}
+
+func GetDefaultCachePath() string {
+	if cache, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(cache, "timesheets", "cache.json")
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".cache", "timesheets", "cache.json")
+	}
+
+	if cwd, err := os.Getwd(); err == nil {
+		return filepath.Join(cwd, "cache.json")
+	}
+
+	panic("Could not determine default cache path")
+}
+
+// Read parses the configuration, expanding ${ENV_VAR} references and resolving the alternatives of secrets
+// (e.g. token_file and token_command) into the secret they refer to.
 func Read(path string) (*Config, error) {
 	var cfg Config
+	var root yaml.Node
 
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := yaml.Unmarshal(content, &cfg); err != nil {
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+
+	// An empty file is an empty configuration
+	if root.Kind == 0 {
+		return &cfg, nil
+	}
+
+	if err := interpolate(&root, filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
+	if err := root.Decode(&cfg); err != nil {
 		return nil, err
 	}
+	cfg.node = &root
 
 	return &cfg, nil
 }
 
-func Print(config *Config) error {
-	content, err := yaml.Marshal(config)
+// Print writes the configuration to the standard output, with its secrets redacted unless they are to be shown.
+func Print(config *Config, secrets bool) error {
+	var root yaml.Node
+	if err := root.Encode(config); err != nil {
+		return err
+	}
+
+	if !secrets {
+		redact(&root)
+	}
+
+	content, err := yaml.Marshal(&root)
 	if err != nil {
 		return err
 	}
@@ -57,3 +286,28 @@ func Print(config *Config) error {
 
 	return nil
 }
+
+// Write saves the configuration to the path, readable only by the user as it may hold secrets.
+func Write(path string, config *Config) error {
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	// A symlinked configuration (e.g. into a dotfiles repository) is written through, instead of being replaced
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so an interrupted run never leaves a truncated configuration behind
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, content, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(temp, path)
+}
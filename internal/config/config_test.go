@@ -0,0 +1,318 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tornermarton/timesheets/internal/entries"
+)
+
+func write(t *testing.T, dir string, name string, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return path
+}
+
+func decode(t *testing.T, node yaml.Node) map[string]any {
+	var spec map[string]any
+	if err := node.Decode(&spec); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return spec
+}
+
+func TestReadSecrets(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TOGGL_WORKSPACE", "6365718")
+	t.Setenv("TOGGL_TOKEN", "toggl$secret")
+
+	write(t, dir, "kronos.token", "kronos-secret\n")
+	path := write(t, dir, "config.yaml", `
+sources:
+  - kind: TogglTrack
+    spec:
+      workspace: ${TOGGL_WORKSPACE}
+      token: ${TOGGL_TOKEN}
+  - kind: TogglTrack
+    spec:
+      workspace: 1
+      token_command: printf 'command-secret\n'
+target:
+  kind: CapsysKronos
+  spec:
+    token_file: kronos.token
+`)
+
+	cfg, err := Read(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	sources := cfg.GetSources()
+	if spec := decode(t, sources[0].Spec); spec["workspace"] != 6365718 || spec["token"] != "toggl$secret" {
+		t.Errorf("expected environment variables to be expanded, got %v", spec)
+	}
+	if spec := decode(t, sources[1].Spec); spec["token"] != "command-secret" {
+		t.Errorf("expected token from command, got %v", spec)
+	}
+	if _, ok := decode(t, sources[1].Spec)["token_command"]; ok {
+		t.Errorf("expected token_command to be replaced, got %v", sources[1].Spec)
+	}
+	if spec := decode(t, cfg.Target.Spec); spec["token"] != "kronos-secret" {
+		t.Errorf("expected token from file, got %v", spec)
+	}
+}
+
+// The sample is meant to be tried out as is, so it must not depend on the environment (e.g. a password manager)
+func TestReadSample(t *testing.T) {
+	cfg, err := Read(filepath.Join("..", "..", "testdata", "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if spec := decode(t, cfg.Target.Spec); spec["token"] != "asdf1234" {
+		t.Errorf("expected token from file, got %v", spec)
+	}
+}
+
+func TestReadSecretsErrors(t *testing.T) {
+	dir := t.TempDir()
+	// Setting it first restores the variable after the test
+	t.Setenv("TIMESHEETS_UNDEFINED", "")
+	os.Unsetenv("TIMESHEETS_UNDEFINED")
+
+	for name, content := range map[string]string{
+		"undefined variable": "source:\n  kind: TogglTrack\n  spec:\n    token: ${TIMESHEETS_UNDEFINED}\n",
+		"missing file":       "source:\n  kind: TogglTrack\n  spec:\n    token_file: missing.token\n",
+		"failing command":    "source:\n  kind: TogglTrack\n  spec:\n    token_command: exit 1\n",
+		"ambiguous token":    "source:\n  kind: TogglTrack\n  spec:\n    token_file: kronos.token\n    token: asdf\n",
+	} {
+		path := write(t, dir, "config.yaml", content)
+		if _, err := Read(path); err == nil || !strings.HasPrefix(err.Error(), "line 4:") {
+			t.Errorf("%s: expected error on line 4, got %v", name, err)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	var spec yaml.Node
+	err := spec.Encode(map[string]any{
+		"token":      "tempo-secret",
+		"jira":       map[string]any{"url": "https://jira.example.com", "token": "jira-secret"},
+		"attributes": map[string]any{"password": "password-secret", "api_key": "key-secret"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var root yaml.Node
+	if err := root.Encode(&Config{Target: &entries.TimeEntryTargetConfig{Kind: "Tempo", Spec: spec}}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	redact(&root)
+
+	content, err := yaml.Marshal(&root)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if strings.Contains(string(content), "secret") || strings.Count(string(content), REDACTED) != 4 {
+		t.Errorf("expected every token to be redacted, got\n%s", content)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	cfg, err := Read(write(t, t.TempDir(), "config.yaml", `
+source:
+  kind: TogglTrack
+  spec:
+    workspace: 1
+    token: asdf
+targets:
+  - kind: CapsysKronos
+    spec:
+      token: asdf
+      defaults:
+        siteId: 34
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	source := decode(t, resolved.Source.Spec)
+	if source["url"] != "https://api.track.toggl.com" || source["timeout"] != "10s" || source["retries"] != 3 {
+		t.Errorf("expected source defaults to be applied, got %v", source)
+	}
+
+	defaults := decode(t, resolved.Targets[0].Spec)["defaults"].(map[string]any)
+	if defaults["siteId"] != 34 || defaults["activityCategoryId"] != 3 {
+		t.Errorf("expected target defaults to be applied, got %v", defaults)
+	}
+
+	if *resolved.TimeZone != "Local" || cfg.TimeZone != nil {
+		t.Errorf("expected the timezone to be resolved on a copy, got %v", resolved.TimeZone)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg, err := Read(write(t, t.TempDir(), "config.yaml", `
+source:
+  kind: TogglTrack
+  spec:
+    workspace: 1
+    token: asdf
+    retry: 5
+targets:
+  - kind: CapsysKronos
+    spec:
+      defaults: { activityTypeID: 5 }
+timezon: UTC
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{
+		`line 7: unknown field "retry"`,
+		`line 11: unknown field "activityTypeID" (did you mean "activityTypeId"?)`,
+		`line 12: unknown field "timezon"`,
+	}
+	if err := cfg.Validate(); err == nil || err.Error() != strings.Join(expected, "\n") {
+		t.Errorf("got\n%v\nwant\n%s", err, strings.Join(expected, "\n"))
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema := Schema()
+	defs := schema["$defs"].(map[string]any)
+
+	for _, kind := range []string{"TogglTrack", "Clockify", "CapsysKronos", "JiraWorklog", "Tempo", "Round", "Drop", "Merge", "Split", "Rules"} {
+		if _, ok := defs[kind]; !ok {
+			t.Errorf("expected a definition of %s", kind)
+		}
+	}
+
+	kronos := defs["CapsysKronos"].(map[string]any)
+	defaults := kronos["properties"].(map[string]any)["defaults"].(map[string]any)["properties"].(map[string]any)
+	if defaults["activityCategoryId"].(map[string]any)["default"] != 3 || defaults["siteId"].(map[string]any)["default"] != 31 {
+		t.Errorf("expected the Kronos defaults, got %v", defaults)
+	}
+
+	// The token is required, but can be given by any of its alternatives
+	properties := kronos["properties"].(map[string]any)
+	if _, ok := properties["token_command"]; !ok || kronos["required"] != nil || len(kronos["oneOf"].([]any)) != 3 {
+		t.Errorf("expected the alternatives of the token, got %v", kronos)
+	}
+
+	target := schema["properties"].(map[string]any)["target"].(map[string]any)
+	if kinds := target["properties"].(map[string]any)["kind"].(map[string]any)["enum"]; len(kinds.([]string)) != 3 {
+		t.Errorf("expected the target kinds, got %v", kinds)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timesheets", "config.yaml")
+	timezone := "UTC"
+
+	var spec yaml.Node
+	spec.Encode(map[string]any{"workspace": 1, "token": "asdf"})
+
+	if err := Write(path, &Config{Source: &entries.TimeEntrySourceConfig{Kind: "TogglTrack", Spec: spec}, TimeZone: &timezone}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("expected the config to be readable only by the user, got %v (%v)", info.Mode(), err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if strings.Contains(string(content), "null") {
+		t.Errorf("expected the unset fields to be left out, got\n%s", content)
+	}
+
+	cfg, err := Read(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if spec := decode(t, cfg.Source.Spec); spec["token"] != "asdf" || *cfg.TimeZone != "UTC" {
+		t.Errorf("expected the config to be read back, got %v", spec)
+	}
+}
+
+func TestWriteSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := write(t, dir, "dotfiles.yaml", "")
+	link := filepath.Join(dir, "config.yaml")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	timezone := "UTC"
+	if err := Write(link, &Config{TimeZone: &timezone}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected the symlink to be kept, got %v (%v)", info, err)
+	}
+	if content, _ := os.ReadFile(target); !strings.Contains(string(content), "timezone: UTC") {
+		t.Errorf("expected the config to be written to the target of the symlink, got %s", content)
+	}
+}
+
+func TestValidateMergeKeys(t *testing.T) {
+	cfg, err := Read(write(t, t.TempDir(), "config.yaml", `
+source:
+  kind: TogglTrack
+  spec:
+    workspace: 1
+    token: asdf
+targets:
+  - kind: JiraWorklog
+    spec: &jira
+      url: https://jira.example.com
+      token: asdf
+  - name: Jira Cloud
+    kind: JiraWorklog
+    spec:
+      <<: *jira
+      email: me@example.com
+  - kind: Tempo
+    spec:
+      account: me
+      token: asdf
+      jira: *jira
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if spec := decode(t, resolved.Targets[1].Spec); spec["url"] != "https://jira.example.com" || spec["email"] != "me@example.com" {
+		t.Errorf("expected the merged spec, got %v", spec)
+	}
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/tornermarton/timesheets/internal/entries"
+	"github.com/tornermarton/timesheets/internal/specs"
+)
+
+// envSchema accepts a reference to an environment variable in place of any value
+var envSchema = map[string]any{"type": "string", "pattern": `\$\{[A-Za-z_][A-Za-z0-9_]*\}`}
+
+// withKinds restricts the kind of a source, target or transform config to the supported ones and selects the
+// schema of the spec by the kind.
+func withKinds[T any](config map[string]any, kinds map[string]entries.Kind[T], defs map[string]any) {
+	names := slices.Sorted(maps.Keys(kinds))
+
+	var conditions []any
+	for _, name := range names {
+		defs[name] = specs.Schema(kinds[name].Spec())
+
+		conditions = append(conditions, map[string]any{
+			"if":   map[string]any{"properties": map[string]any{"kind": map[string]any{"const": name}}},
+			"then": map[string]any{"properties": map[string]any{"spec": map[string]any{"$ref": "#/$defs/" + name}}},
+		})
+	}
+
+	config["properties"].(map[string]any)["kind"] = map[string]any{"type": "string", "enum": names}
+	config["allOf"] = conditions
+}
+
+// withInterpolation allows what Read resolves: environment variables in place of values of any type, and the
+// alternatives of secrets (e.g. token_file and token_command) in place of the secrets.
+func withInterpolation(schema map[string]any) {
+	_, pattern := schema["pattern"]
+	_, enum := schema["enum"]
+
+	// Plain strings accept references already
+	if schema["type"] == "integer" || schema["type"] == "number" || schema["type"] == "boolean" || pattern || enum {
+		typed := maps.Clone(schema)
+		delete(typed, "default")
+
+		for key := range schema {
+			if key != "default" {
+				delete(schema, key)
+			}
+		}
+		schema["anyOf"] = []any{typed, envSchema}
+		return
+	}
+
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		for name, property := range maps.Clone(properties) {
+			withInterpolation(property.(map[string]any))
+
+			if !isSecret(name) {
+				continue
+			}
+
+			alternatives := []any{map[string]any{"required": []string{name}}}
+			for _, suffix := range secretSuffixes {
+				properties[name+suffix] = map[string]any{"type": "string"}
+				alternatives = append(alternatives, map[string]any{"required": []string{name + suffix}})
+			}
+
+			// A required secret can be given by any (but only one) of its alternatives
+			if required, ok := schema["required"].([]string); ok && slices.Contains(required, name) {
+				schema["required"] = slices.DeleteFunc(slices.Clone(required), func(key string) bool { return key == name })
+				if len(schema["required"].([]string)) == 0 {
+					delete(schema, "required")
+				}
+				schema["oneOf"] = alternatives
+			}
+		}
+	}
+
+	for _, key := range []string{"items", "additionalProperties"} {
+		if inner, ok := schema[key].(map[string]any); ok {
+			withInterpolation(inner)
+		}
+	}
+}
+
+// Schema returns the JSON Schema of the configuration file, selecting the schema of the specs of sources,
+// targets and transforms by their kind.
+func Schema() map[string]any {
+	schema := specs.Schema(Config{})
+	properties := schema["properties"].(map[string]any)
+
+	// The kinds are set afterwards, so they remain restricted to the supported ones
+	withInterpolation(schema)
+
+	defs := map[string]any{}
+	for _, name := range []string{"source", "sources"} {
+		withKinds(configSchema(properties[name]), entries.TimeEntrySourceKinds, defs)
+	}
+	for _, name := range []string{"target", "targets"} {
+		withKinds(configSchema(properties[name]), entries.TimeEntryTargetKinds, defs)
+	}
+	withKinds(configSchema(properties["transforms"]), entries.TimeEntryTransformKinds, defs)
+
+	for _, def := range defs {
+		withInterpolation(def.(map[string]any))
+	}
+
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "timesheets configuration"
+	schema["$defs"] = defs
+
+	return schema
+}
+
+// configSchema returns the schema of a single config, given the schema of the config or a list of them.
+func configSchema(property any) map[string]any {
+	schema := property.(map[string]any)
+	if items, ok := schema["items"].(map[string]any); ok {
+		return items
+	}
+	return schema
+}
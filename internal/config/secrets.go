@@ -0,0 +1,152 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Only the braced form is expanded, so values containing a bare $ (e.g. tokens) are left intact
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// secretSuffixes are the alternatives of a secret value, e.g. token_file and token_command for the token.
+var secretSuffixes = []string{"_file", "_command"}
+
+// secretKeys are the (normalized) spec fields holding credentials
+var secretKeys = []string{"token", "password", "apikey"}
+
+// REDACTED replaces the secrets whenever the configuration is printed
+const REDACTED = "<redacted>"
+
+// isSecret returns whether the key holds a credential, e.g. token, password, api_key or apiKey.
+func isSecret(key string) bool {
+	key = strings.NewReplacer("_", "", "-", "", " ", "").Replace(strings.ToLower(key))
+	return slices.Contains(secretKeys, key)
+}
+
+func expandEnv(node *yaml.Node) error {
+	var err error
+	expanded := envPattern.ReplaceAllStringFunc(node.Value, func(match string) string {
+		name := envPattern.FindStringSubmatch(match)[1]
+		value, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("line %d: environment variable %s is not set", node.Line, name)
+		}
+		return value
+	})
+	if err != nil {
+		return err
+	}
+
+	if expanded != node.Value {
+		node.Value = expanded
+		// Plain scalars are resolved again, so e.g. a workspace given as ${WORKSPACE} is still a number
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+	}
+
+	return nil
+}
+
+// readSecret reads the secret from a file (relative to the directory of the config) or from the output of a
+// shell command (e.g. a password manager), without the trailing newline.
+func readSecret(suffix string, value string, dir string) (string, error) {
+	switch suffix {
+	case "_file":
+		path := value
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	default:
+		var stdout bytes.Buffer
+
+		// Password managers may prompt for a passphrase, so the terminal is kept available for them
+		command := exec.Command("sh", "-c", value)
+		command.Stdin = os.Stdin
+		command.Stdout = &stdout
+		command.Stderr = os.Stderr
+
+		if err := command.Run(); err != nil {
+			return "", fmt.Errorf("command %q failed: %w", value, err)
+		}
+		return strings.TrimRight(stdout.String(), "\r\n"), nil
+	}
+}
+
+// interpolate expands the environment variables of every value and replaces the alternatives of secrets
+// (e.g. token_file and token_command) with the secret they refer to.
+func interpolate(node *yaml.Node, dir string) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return expandEnv(node)
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := interpolate(child, dir); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		keys := map[string]bool{}
+		for i := 0; i < len(node.Content); i += 2 {
+			keys[node.Content[i].Value] = true
+		}
+
+		for i := 0; i < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if err := interpolate(value, dir); err != nil {
+				return err
+			}
+
+			for _, suffix := range secretSuffixes {
+				name, ok := strings.CutSuffix(key.Value, suffix)
+				if !ok || !isSecret(name) {
+					continue
+				}
+
+				if keys[name] {
+					return fmt.Errorf("line %d: only one of %s and %s can be given", key.Line, name, key.Value)
+				}
+
+				secret, err := readSecret(suffix, value.Value, dir)
+				if err != nil {
+					return fmt.Errorf("line %d: cannot read %s: %w", key.Line, key.Value, err)
+				}
+
+				keys[name] = true
+				key.Value = name
+				value.Value, value.Tag, value.Style = secret, "!!str", 0
+			}
+		}
+	}
+
+	return nil
+}
+
+// redact replaces the value of every secret within the node tree.
+func redact(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			if key, value := node.Content[i], node.Content[i+1]; isSecret(key.Value) && value.Kind == yaml.ScalarNode {
+				value.Value, value.Tag, value.Style = REDACTED, "!!str", 0
+			}
+		}
+	}
+
+	for _, child := range node.Content {
+		redact(child)
+	}
+}
@@ -5,5 +5,17 @@ import (
 )
 
 var NOW = time.Now()
-var TODAY = time.Date(NOW.Year(), NOW.Month(), NOW.Day(), 0, 0, 0, 0, NOW.Location())
-var TOMORROW = TODAY.Add(24 * time.Hour)
+
+// LOOKBACK widens the pulled period, so entries started before it can still contribute (e.g. after splitting).
+var LOOKBACK = 24 * time.Hour
+
+// Exit codes distinguish runs needing attention (e.g. from cron) by their cause
+const (
+	EXIT_OK = 0
+	// EXIT_ERROR is returned when nothing could be synchronized (e.g. invalid configuration, unreachable source)
+	EXIT_ERROR = 1
+	// EXIT_PARTIAL is returned when some of the entries failed to synchronize
+	EXIT_PARTIAL = 2
+	// EXIT_INTERRUPTED follows the shell convention for SIGINT
+	EXIT_INTERRUPTED = 130
+)
@@ -1,6 +1,11 @@
 package entries
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
 	"time"
 
 	"charm.land/lipgloss/v2"
@@ -12,11 +17,59 @@ var primary = lipgloss.NewStyle().Bold(true)
 var secondary = lipgloss.NewStyle().Faint(true)
 
 type TimeEntry struct {
-	Issue       string
-	From        time.Time
-	Till        time.Time
-	Description string
-	Tags        []string
+	Id string `json:"id"`
+	// Source is the name of the source the entry was pulled from.
+	Source string `json:"source,omitempty"`
+
+	Issue       string    `json:"issue"`
+	From        time.Time `json:"from"`
+	Till        time.Time `json:"till"`
+	Description string    `json:"description"`
+	Tags        []string  `json:"tags,omitempty"`
+
+	// Attributes holds additional source specific values (e.g. project, client) entries can be matched on.
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// Fields holds target specific values (e.g. activity type), set by rules or read back from targets.
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+// Checksum identifies the content of the entry, it changes whenever a field relevant to targets changes.
+func (te TimeEntry) Checksum() string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%s\x00%s", te.Issue, te.From.UTC().Format(time.RFC3339Nano), te.Till.UTC().Format(time.RFC3339Nano), te.Description)
+	for _, tag := range te.Tags {
+		fmt.Fprintf(hash, "\x00%s", tag)
+	}
+	for _, key := range slices.Sorted(maps.Keys(te.Fields)) {
+		fmt.Fprintf(hash, "\x00%s=%v", key, te.Fields[key])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// StartsWithin returns whether the entry starts within [from, till), an entry belongs to the window its start falls into.
+func (te TimeEntry) StartsWithin(from time.Time, till time.Time) bool {
+	return !te.From.Before(from) && te.From.Before(till)
+}
+
+// Overlaps returns whether the two entries share any period of time.
+func (te TimeEntry) Overlaps(other TimeEntry) bool {
+	return te.From.Before(other.Till) && other.From.Before(te.Till)
+}
+
+// FindOverlaps returns the pairs of entries pulled from different sources that overlap, entries must be sorted by From.
+func FindOverlaps(entries []TimeEntry) [][2]TimeEntry {
+	var overlaps [][2]TimeEntry
+	for i, entry := range entries {
+		for _, other := range entries[i+1:] {
+			if !other.From.Before(entry.Till) {
+				break
+			}
+			if entry.Source != other.Source && entry.Overlaps(other) {
+				overlaps = append(overlaps, [2]TimeEntry{entry, other})
+			}
+		}
+	}
+	return overlaps
 }
 
 func (te TimeEntry) String(location *time.Location) string {
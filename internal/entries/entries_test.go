@@ -0,0 +1,22 @@
+package entries
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFindOverlaps(t *testing.T) {
+	at := func(hour int, minute int) time.Time { return time.Date(2025, 6, 1, hour, minute, 0, 0, time.UTC) }
+
+	entries := []TimeEntry{
+		{Id: "a", Source: "toggl", From: at(9, 0), Till: at(10, 0)},
+		{Id: "b", Source: "calendar", From: at(9, 30), Till: at(9, 45)},
+		{Id: "c", Source: "toggl", From: at(9, 50), Till: at(10, 30)},
+		{Id: "d", Source: "calendar", From: at(10, 30), Till: at(11, 0)},
+	}
+
+	overlaps := FindOverlaps(entries)
+	if len(overlaps) != 1 || overlaps[0][0].Id != "a" || overlaps[0][1].Id != "b" {
+		t.Errorf("unexpected overlaps: %+v", overlaps)
+	}
+}
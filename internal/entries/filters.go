@@ -0,0 +1,47 @@
+package entries
+
+import (
+	"slices"
+	"strings"
+)
+
+type TimeEntryMatcher struct {
+	// Issues are matched as prefixes, e.g. "ABC-" matches every issue of the ABC project
+	Issues []string `yaml:"issues,omitempty"`
+	Tags   []string `yaml:"tags,omitempty"`
+}
+
+func (m TimeEntryMatcher) IsEmpty() bool {
+	return len(m.Issues) == 0 && len(m.Tags) == 0
+}
+
+// Matches returns whether any of the issue prefixes or tags applies to the entry.
+func (m TimeEntryMatcher) Matches(entry TimeEntry) bool {
+	for _, prefix := range m.Issues {
+		if strings.HasPrefix(entry.Issue, prefix) {
+			return true
+		}
+	}
+
+	for _, tag := range m.Tags {
+		if slices.Contains(entry.Tags, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type TimeEntryFilter struct {
+	Include TimeEntryMatcher `yaml:"include,omitempty"`
+	Exclude TimeEntryMatcher `yaml:"exclude,omitempty"`
+}
+
+// Matches returns whether the entry is included (everything is when no include is given) and not excluded.
+func (f TimeEntryFilter) Matches(entry TimeEntry) bool {
+	if !f.Include.IsEmpty() && !f.Include.Matches(entry) {
+		return false
+	}
+
+	return !f.Exclude.Matches(entry)
+}
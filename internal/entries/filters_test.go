@@ -0,0 +1,32 @@
+package entries
+
+import (
+	"testing"
+)
+
+func TestTimeEntryFilterMatches(t *testing.T) {
+	filter := TimeEntryFilter{
+		Include: TimeEntryMatcher{Issues: []string{"CLX-"}, Tags: []string{"client-x"}},
+		Exclude: TimeEntryMatcher{Tags: []string{"internal"}},
+	}
+
+	cases := []struct {
+		entry   TimeEntry
+		matches bool
+	}{
+		{TimeEntry{Issue: "CLX-1"}, true},
+		{TimeEntry{Issue: "ABC-1", Tags: []string{"client-x"}}, true},
+		{TimeEntry{Issue: "ABC-1", Tags: []string{"meeting"}}, false},
+		{TimeEntry{Issue: "CLX-1", Tags: []string{"internal"}}, false},
+	}
+
+	for _, c := range cases {
+		if matches := filter.Matches(c.entry); matches != c.matches {
+			t.Errorf("Matches(%+v) = %t; want %t", c.entry, matches, c.matches)
+		}
+	}
+
+	if !(TimeEntryFilter{}).Matches(TimeEntry{Issue: "ABC-1"}) {
+		t.Errorf("expected empty filter to match everything")
+	}
+}
@@ -0,0 +1,118 @@
+package entries
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/tornermarton/timesheets/internal/cache"
+)
+
+// issueNotFound marks the failed lookups that found the issue to be missing, unlike other failures (e.g.
+// an expired token, an unavailable server) these are remembered.
+type issueNotFound struct {
+	error
+}
+
+type issueResult[T any] struct {
+	value T
+	err   error
+}
+
+// issueCache remembers the outcome of looking up issues, so every issue is requested at most once per
+// run. Found issues are also remembered across runs when a persistent cache is configured.
+type issueCache[T any] struct {
+	// prefix separates the issues of different targets (and instances) within the persistent cache
+	prefix string
+	cache  *cache.Cache
+
+	mutex   sync.Mutex
+	results map[string]issueResult[T]
+	// pending holds the lookups in flight, closed once done, so concurrent lookups of an issue wait for them
+	pending map[string]chan struct{}
+}
+
+func (c *issueCache[T]) remember(issue string, value T, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.results == nil {
+		c.results = map[string]issueResult[T]{}
+	}
+	c.results[issue] = issueResult[T]{value: value, err: err}
+}
+
+// put stores an issue found by other means (e.g. under its other identifier).
+func (c *issueCache[T]) put(issue string, value T) {
+	c.remember(issue, value, nil)
+	c.cache.Put(c.prefix+issue, value)
+}
+
+// claim returns the remembered result of the issue or, if there is none, the channel to close once the
+// issue is looked up, waiting for the lookup of the issue already in flight (if any).
+func (c *issueCache[T]) claim(ctx context.Context, issue string) (*issueResult[T], chan struct{}, error) {
+	for {
+		c.mutex.Lock()
+		if result, ok := c.results[issue]; ok {
+			c.mutex.Unlock()
+			return &result, nil, nil
+		}
+
+		pending, ok := c.pending[issue]
+		if !ok {
+			if c.pending == nil {
+				c.pending = map[string]chan struct{}{}
+			}
+			done := make(chan struct{})
+			c.pending[issue] = done
+			c.mutex.Unlock()
+			return nil, done, nil
+		}
+		c.mutex.Unlock()
+
+		select {
+		case <-pending:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+func (c *issueCache[T]) release(issue string, done chan struct{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.pending, issue)
+	close(done)
+}
+
+func (c *issueCache[T]) resolve(ctx context.Context, issue string, lookup func(ctx context.Context, issue string) (T, error)) (T, error) {
+	var value T
+
+	result, done, err := c.claim(ctx, issue)
+	if err != nil {
+		return value, err
+	}
+	if result != nil {
+		return result.value, result.err
+	}
+	defer c.release(issue, done)
+
+	if c.cache.Get(c.prefix+issue, &value) {
+		c.remember(issue, value, nil)
+		return value, nil
+	}
+
+	value, err = lookup(ctx, issue)
+
+	// Only a missing issue is remembered, other failures (including interrupts) tell nothing about the issue
+	var notFound issueNotFound
+	switch {
+	case err == nil:
+		c.put(issue, value)
+	case errors.As(err, &notFound):
+		c.remember(issue, value, err)
+	}
+
+	return value, err
+}
@@ -0,0 +1,81 @@
+package entries
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tornermarton/timesheets/internal/cache"
+)
+
+func TestIssueCacheResolve(t *testing.T) {
+	lookups := map[string]int{}
+	lookup := func(ctx context.Context, issue string) (bool, error) {
+		lookups[issue]++
+		switch issue {
+		case "XYZ-9":
+			return false, issueNotFound{fmt.Errorf("invalid issue")}
+		case "ERR-1":
+			return false, fmt.Errorf("server unavailable")
+		}
+		return true, nil
+	}
+
+	path := filepath.Join(t.TempDir(), "cache.json")
+	cache_, _ := cache.Open(path, time.Hour)
+
+	issues := issueCache[bool]{prefix: "Test/", cache: cache_}
+	for range 3 {
+		issues.resolve(context.Background(), "ABC-1", lookup)
+		if _, err := issues.resolve(context.Background(), "XYZ-9", lookup); err == nil {
+			t.Errorf("expected the lookup error to be remembered")
+		}
+		issues.resolve(context.Background(), "ERR-1", lookup)
+	}
+
+	// Failures other than a missing issue are retried, they tell nothing about the issue
+	if lookups["ABC-1"] != 1 || lookups["XYZ-9"] != 1 || lookups["ERR-1"] != 3 {
+		t.Errorf("expected every issue to be looked up once per run, got %v", lookups)
+	}
+
+	cache_.Save()
+	reopened, _ := cache.Open(path, time.Hour)
+
+	// Only found issues are remembered across runs, the others might be created in the meantime
+	issues = issueCache[bool]{prefix: "Test/", cache: reopened}
+	issues.resolve(context.Background(), "ABC-1", lookup)
+	issues.resolve(context.Background(), "XYZ-9", lookup)
+
+	if lookups["ABC-1"] != 1 || lookups["XYZ-9"] != 2 {
+		t.Errorf("unexpected lookups across runs: %v", lookups)
+	}
+}
+
+func TestIssueCacheResolveConcurrently(t *testing.T) {
+	var lookups atomic.Int32
+	lookup := func(ctx context.Context, issue string) (bool, error) {
+		lookups.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return true, nil
+	}
+
+	issues := issueCache[bool]{prefix: "Test/"}
+
+	var group sync.WaitGroup
+	for range 8 {
+		group.Go(func() {
+			if found, err := issues.resolve(context.Background(), "ABC-1", lookup); !found || err != nil {
+				t.Errorf("unexpected result: %v (%v)", found, err)
+			}
+		})
+	}
+	group.Wait()
+
+	if lookups.Load() != 1 {
+		t.Errorf("expected concurrent lookups of an issue to wait for the one in flight, got %d lookups", lookups.Load())
+	}
+}
@@ -0,0 +1,142 @@
+package entries
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tornermarton/timesheets/internal/cache"
+	"github.com/tornermarton/timesheets/internal/specs"
+)
+
+// Verifier is implemented by the sources and targets able to check their credentials, by sending an
+// authenticated request without any effect.
+type Verifier interface {
+	Verify(ctx context.Context) error
+}
+
+// environment holds what sources, targets and transforms are created with besides their spec.
+type environment struct {
+	// location is the default timezone of the kinds unaware of timezones
+	location *time.Location
+	cache    *cache.Cache
+}
+
+// Kind describes a kind of source, target or transform: the spec it is configured with and how it is created.
+type Kind[T any] struct {
+	// Spec returns a pointer to a new spec of the kind, filled with its defaults.
+	Spec   func() any
+	create func(spec any, environment environment) (T, error)
+}
+
+func newKind[T any, S any](defaults func() S, create func(spec S, environment environment) (T, error)) Kind[T] {
+	return Kind[T]{
+		Spec: func() any {
+			spec := defaults()
+			return &spec
+		},
+		create: func(spec any, environment environment) (T, error) {
+			return create(*spec.(*S), environment)
+		},
+	}
+}
+
+// TimeEntrySourceKinds are the supported kinds of sources by their name.
+var TimeEntrySourceKinds = map[string]Kind[TimeEntrySource]{
+	"TogglTrack": newKind(defaultTogglTrackSpec, func(spec TogglTrackSpec, _ environment) (TimeEntrySource, error) {
+		return createTogglTrack(spec)
+	}),
+	"Clockify": newKind(defaultClockifySpec, func(spec ClockifySpec, _ environment) (TimeEntrySource, error) {
+		return createClockify(spec)
+	}),
+}
+
+// TimeEntryTargetKinds are the supported kinds of targets by their name.
+var TimeEntryTargetKinds = map[string]Kind[TimeEntryTarget]{
+	"CapsysKronos": newKind(defaultCapsysKronosSpec, func(spec CapsysKronosSpec, environment environment) (TimeEntryTarget, error) {
+		return createCapsysKronos(spec, environment.location, environment.cache)
+	}),
+	"JiraWorklog": newKind(defaultJiraWorklogSpec, func(spec JiraWorklogSpec, environment environment) (TimeEntryTarget, error) {
+		return createJiraWorklog(spec, environment.cache)
+	}),
+	"Tempo": newKind(defaultTempoSpec, func(spec TempoSpec, environment environment) (TimeEntryTarget, error) {
+		return createTempo(spec, environment.location, environment.cache)
+	}),
+}
+
+// TimeEntryTransformKinds are the supported kinds of transforms by their name.
+var TimeEntryTransformKinds = map[string]Kind[TimeEntryTransform]{
+	"Round": newKind(defaultRoundSpec, func(spec RoundSpec, environment environment) (TimeEntryTransform, error) {
+		return createRound(spec, environment.location)
+	}),
+	"Drop": newKind(defaultDropSpec, func(spec DropSpec, _ environment) (TimeEntryTransform, error) {
+		return createDrop(spec)
+	}),
+	"Merge": newKind(defaultMergeSpec, func(spec MergeSpec, environment environment) (TimeEntryTransform, error) {
+		return createMerge(spec, environment.location)
+	}),
+	"Split": newKind(defaultSplitSpec, func(spec SplitSpec, environment environment) (TimeEntryTransform, error) {
+		return createSplit(spec, environment.location)
+	}),
+	"Rules": newKind(defaultRulesSpec, func(spec RulesSpec, environment environment) (TimeEntryTransform, error) {
+		return createRules(spec, environment.location)
+	}),
+}
+
+// withLine prefixes the error with the line of the spec, so it can be found in the config.
+func withLine(spec *yaml.Node, err error) error {
+	if spec.Line == 0 {
+		return err
+	}
+	return fmt.Errorf("line %d: %w", spec.Line, err)
+}
+
+// decode decodes the spec of the kind strictly, on top of its defaults.
+func (k Kind[T]) decode(spec *yaml.Node) (any, error) {
+	out := k.Spec()
+	if err := specs.Decode(spec, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// create looks up the kind and creates the source, target or transform from the spec, subject names what
+// is created in errors.
+func create[T any](kinds map[string]Kind[T], subject string, kind string, spec *yaml.Node, environment environment) (T, error) {
+	var zero T
+
+	kind_, ok := kinds[kind]
+	if !ok {
+		return zero, withLine(spec, fmt.Errorf("unsupported time entry %s: %s", subject, kind))
+	}
+
+	decoded, err := kind_.decode(spec)
+	if err != nil {
+		return zero, err
+	}
+
+	created, err := kind_.create(decoded, environment)
+	if err != nil {
+		return zero, withLine(spec, err)
+	}
+
+	return created, nil
+}
+
+// resolve returns the spec with every default of the kind applied, after making sure it is valid.
+func resolve[T any](kinds map[string]Kind[T], subject string, kind string, spec *yaml.Node, environment environment) (yaml.Node, error) {
+	var resolved yaml.Node
+
+	if _, err := create(kinds, subject, kind, spec, environment); err != nil {
+		return resolved, err
+	}
+
+	decoded, _ := kinds[kind].decode(spec)
+	if err := resolved.Encode(decoded); err != nil {
+		return resolved, err
+	}
+
+	return resolved, nil
+}
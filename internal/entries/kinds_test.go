@@ -0,0 +1,77 @@
+package entries
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func parseSpec(t *testing.T, content string) yaml.Node {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &node); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return *node.Content[0]
+}
+
+func TestNewTimeEntrySourceSpec(t *testing.T) {
+	source, err := NewTimeEntrySource(TimeEntrySourceConfig{Kind: "TogglTrack", Spec: parseSpec(t, "workspace: 1\ntoken: asdf\nissue: [project]\n")})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	toggl := source.(*TogglTrack)
+	if toggl.Url.Host != "api.track.toggl.com" || toggl.Client.Options.Retries != 3 {
+		t.Errorf("expected the defaults to be applied, got %+v", toggl)
+	}
+	if len(toggl.Issue) != 1 || toggl.Issue[0] != "project" {
+		t.Errorf("expected the default issue strategies to be replaced, got %v", toggl.Issue)
+	}
+}
+
+func TestNewTimeEntryTargetSpecProblems(t *testing.T) {
+	spec := parseSpec(t, "token: asdf\ntags:\n  development: { activityTypeID: 5 }\ndefaults:\n  siteId: many\n")
+
+	_, err := NewTimeEntryTarget(TimeEntryTargetConfig{Kind: "CapsysKronos", Spec: spec}, time.UTC, nil)
+	if err == nil || !strings.Contains(err.Error(), `line 3: unknown field "activityTypeID"`) || !strings.Contains(err.Error(), "line 5: cannot unmarshal") {
+		t.Errorf("expected every problem with its line, got %v", err)
+	}
+
+	_, err = NewTimeEntryTarget(TimeEntryTargetConfig{Kind: "CapsysKronos", Spec: parseSpec(t, "url: https://jira.example.com\n")}, time.UTC, nil)
+	if err == nil || err.Error() != "line 1: invalid or missing 'token' spec for CapsysKronos target" {
+		t.Errorf("expected the missing token with the line of the spec, got %v", err)
+	}
+
+	if _, err := NewTimeEntryTarget(TimeEntryTargetConfig{Kind: "Harvest"}, time.UTC, nil); err == nil {
+		t.Errorf("expected an unsupported kind to be rejected")
+	}
+}
+
+func TestNewTimeEntryTargetSpecOtherFields(t *testing.T) {
+	spec := parseSpec(t, "token: asdf\ntags:\n  internal: { activityTypeId: 12, issueKey: ABC-9 }\n")
+
+	target, err := NewTimeEntryTarget(TimeEntryTargetConfig{Kind: "CapsysKronos", Spec: spec}, time.UTC, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	entry, _ := target.(*CapsysKronos).convertEntry(TimeEntry{Issue: "ABC-1", Tags: []string{"internal"}})
+	if entry.WorklogInput.IssueKey != "ABC-9" || entry.WorklogInput.ActivityTypeId != 12 {
+		t.Errorf("expected the other fields of tags to be set as they are, got %+v", entry.WorklogInput)
+	}
+}
+
+func TestResolveTimeEntryTransformConfig(t *testing.T) {
+	config, err := ResolveTimeEntryTransformConfig(TimeEntryTransformConfig{Kind: "Merge", Spec: parseSpec(t, "gap: 5m\n")}, time.UTC)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var spec map[string]any
+	config.Spec.Decode(&spec)
+	if spec["gap"] != "5m0s" || spec["scope"] != "consecutive" || spec["descriptions"] != "match" {
+		t.Errorf("expected the defaults to be applied, got %v", spec)
+	}
+}
@@ -0,0 +1,294 @@
+package entries
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+type RuleMatch struct {
+	Issue       *regexp.Regexp
+	Description *regexp.Regexp
+	// Tags must all be present on the entry
+	Tags       []string
+	Attributes map[string]*regexp.Regexp
+	Weekdays   []time.Weekday
+	// From and Till bound the (local) start time of the entry as offsets from midnight, till being exclusive
+	From *time.Duration
+	Till *time.Duration
+}
+
+func (m RuleMatch) Matches(entry TimeEntry, location *time.Location) bool {
+	if m.Issue != nil && !m.Issue.MatchString(entry.Issue) {
+		return false
+	}
+
+	// Sources keep the original text, which still contains the issue (or is the issue itself without brackets)
+	if m.Description != nil && !m.Description.MatchString(cmp.Or(entry.Attributes["description"], entry.Description)) {
+		return false
+	}
+
+	for _, tag := range m.Tags {
+		if !slices.Contains(entry.Tags, tag) {
+			return false
+		}
+	}
+
+	for key, pattern := range m.Attributes {
+		if value, ok := entry.Attributes[key]; !ok || !pattern.MatchString(value) {
+			return false
+		}
+	}
+
+	local := entry.From.In(location)
+
+	if len(m.Weekdays) > 0 && !slices.Contains(m.Weekdays, local.Weekday()) {
+		return false
+	}
+
+	offset := local.Sub(time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location))
+	if m.From != nil && offset < *m.From {
+		return false
+	}
+	if m.Till != nil && offset >= *m.Till {
+		return false
+	}
+
+	return true
+}
+
+type RuleSet struct {
+	Issue       *string
+	Description *string
+	Tags        []string
+	Fields      map[string]any
+}
+
+func (s RuleSet) Apply(entry TimeEntry) TimeEntry {
+	if s.Issue != nil {
+		entry.Issue = *s.Issue
+	}
+
+	if s.Description != nil {
+		entry.Description = *s.Description
+	}
+
+	if s.Tags != nil {
+		entry.Tags = slices.Clone(s.Tags)
+	}
+
+	if s.Fields != nil {
+		fields := maps.Clone(entry.Fields)
+		if fields == nil {
+			fields = map[string]any{}
+		}
+		maps.Copy(fields, s.Fields)
+		entry.Fields = fields
+	}
+
+	return entry
+}
+
+type Rule struct {
+	Match RuleMatch
+	Set   RuleSet
+}
+
+type Rules struct {
+	// Mode is either "first" (only the first matching rule applies) or "all" (every matching rule
+	// applies in order, each seeing the changes of the previous ones)
+	Mode     string
+	Rules    []Rule
+	Location *time.Location
+}
+
+func (r *Rules) TransformTimeEntries(entries []TimeEntry) ([]TimeEntry, error) {
+	out := make([]TimeEntry, 0, len(entries))
+	for _, entry := range entries {
+		for _, rule := range r.Rules {
+			if !rule.Match.Matches(entry, r.Location) {
+				continue
+			}
+
+			entry = rule.Set.Apply(entry)
+
+			if r.Mode == "first" {
+				break
+			}
+		}
+
+		out = append(out, entry)
+	}
+
+	return out, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+type RuleMatchSpec struct {
+	Issue       string            `yaml:"issue,omitempty"`
+	Description string            `yaml:"description,omitempty"`
+	Tags        []string          `yaml:"tags,omitempty"`
+	Attributes  map[string]string `yaml:"attributes,omitempty"`
+	// Projects and clients are the most common attributes, so they can be matched directly
+	Project  string   `yaml:"project,omitempty"`
+	Client   string   `yaml:"client,omitempty"`
+	Weekdays []string `yaml:"weekdays,omitempty"`
+	From     string   `yaml:"from,omitempty"`
+	Till     string   `yaml:"till,omitempty"`
+}
+
+type RuleSetSpec struct {
+	Issue       *string `yaml:"issue,omitempty"`
+	Description *string `yaml:"description,omitempty"`
+	// Tags replace the tags of the entry, an empty list removes them
+	Tags   *[]string      `yaml:"tags,omitempty"`
+	Fields map[string]any `yaml:"fields,omitempty"`
+}
+
+type RuleSpec struct {
+	Match RuleMatchSpec `yaml:"match"`
+	Set   RuleSetSpec   `yaml:"set"`
+}
+
+type RulesSpec struct {
+	Mode  string     `yaml:"mode" schema:"enum=first|all"`
+	Rules []RuleSpec `yaml:"rules" schema:"required"`
+}
+
+func defaultRulesSpec() RulesSpec {
+	return RulesSpec{
+		Mode: "first",
+	}
+}
+
+func createRuleMatch(spec RuleMatchSpec) (RuleMatch, error) {
+	var match RuleMatch
+
+	if spec.Issue != "" {
+		issue, err := regexp.Compile(spec.Issue)
+		if err != nil {
+			return RuleMatch{}, fmt.Errorf("invalid 'issue' match: %w", err)
+		}
+		match.Issue = issue
+	}
+
+	if spec.Description != "" {
+		description, err := regexp.Compile(spec.Description)
+		if err != nil {
+			return RuleMatch{}, fmt.Errorf("invalid 'description' match: %w", err)
+		}
+		match.Description = description
+	}
+
+	match.Tags = spec.Tags
+
+	var attributes = maps.Clone(spec.Attributes)
+	if attributes == nil {
+		attributes = map[string]string{}
+	}
+	if spec.Project != "" {
+		attributes["project"] = spec.Project
+	}
+	if spec.Client != "" {
+		attributes["client"] = spec.Client
+	}
+
+	for key, value := range attributes {
+		pattern, err := regexp.Compile(value)
+		if err != nil {
+			return RuleMatch{}, fmt.Errorf("invalid '%s' attribute match: %w", key, err)
+		}
+
+		if match.Attributes == nil {
+			match.Attributes = map[string]*regexp.Regexp{}
+		}
+		match.Attributes[key] = pattern
+	}
+
+	for _, weekday := range spec.Weekdays {
+		weekday_, ok := weekdays[strings.ToLower(weekday)]
+		if !ok {
+			return RuleMatch{}, fmt.Errorf("invalid 'weekdays' match: %v", weekday)
+		}
+		match.Weekdays = append(match.Weekdays, weekday_)
+	}
+
+	if spec.From != "" {
+		from, err := parseTimeOfDay(spec.From)
+		if err != nil {
+			return RuleMatch{}, fmt.Errorf("invalid 'from' match: %w", err)
+		}
+		match.From = &from
+	}
+
+	if spec.Till != "" {
+		till, err := parseTimeOfDay(spec.Till)
+		if err != nil {
+			return RuleMatch{}, fmt.Errorf("invalid 'till' match: %w", err)
+		}
+		match.Till = &till
+	}
+
+	return match, nil
+}
+
+func createRuleSet(spec RuleSetSpec) RuleSet {
+	var set = RuleSet{
+		Issue:       spec.Issue,
+		Description: spec.Description,
+		Fields:      spec.Fields,
+	}
+
+	if spec.Tags != nil {
+		set.Tags = append([]string{}, *spec.Tags...)
+	}
+
+	return set
+}
+
+func createRules(spec RulesSpec, location *time.Location) (*Rules, error) {
+	if spec.Mode != "first" && spec.Mode != "all" {
+		return nil, fmt.Errorf("invalid 'mode' spec for Rules transform: %s (expected first or all)", spec.Mode)
+	}
+
+	if len(spec.Rules) == 0 {
+		return nil, fmt.Errorf("invalid or missing 'rules' spec for Rules transform")
+	}
+
+	var rules []Rule
+	for i, rule := range spec.Rules {
+		match, err := createRuleMatch(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule #%d for Rules transform: %w", i, err)
+		}
+
+		rules = append(rules, Rule{Match: match, Set: createRuleSet(rule.Set)})
+	}
+
+	return &Rules{
+		Mode:     spec.Mode,
+		Rules:    rules,
+		Location: location,
+	}, nil
+}
@@ -0,0 +1,58 @@
+package entries
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRules(t *testing.T) {
+	location, _ := time.LoadLocation("Europe/Budapest")
+
+	issue, description, internal := "OPS-1", "Daily standup", "INT-1"
+	rules, err := createRules(RulesSpec{
+		Mode: "all",
+		Rules: []RuleSpec{
+			{
+				Match: RuleMatchSpec{Description: "(?i)standup", Weekdays: []string{"Monday", "tuesday"}, From: "09:00", Till: "10:00"},
+				Set:   RuleSetSpec{Issue: &issue, Description: &description, Tags: &[]string{"meeting"}},
+			},
+			{
+				Match: RuleMatchSpec{Tags: []string{"meeting"}},
+				Set:   RuleSetSpec{Fields: map[string]any{"activityTypeId": 12}},
+			},
+			{
+				Match: RuleMatchSpec{Project: "^Internal$"},
+				Set:   RuleSetSpec{Issue: &internal},
+			},
+		},
+	}, location)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	monday := time.Date(2025, 6, 2, 9, 30, 0, 0, location)
+	entries, err := rules.TransformTimeEntries([]TimeEntry{
+		{Issue: "Standup", From: monday, Attributes: map[string]string{"description": "Standup"}},
+		{Issue: "Standup", From: monday.Add(time.Hour), Attributes: map[string]string{"description": "Standup"}},
+		{Issue: "ABC-1", From: monday, Attributes: map[string]string{"project": "Internal"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if entries[0].Issue != "OPS-1" || entries[0].Description != "Daily standup" || entries[0].Fields["activityTypeId"] != 12 {
+		t.Errorf("expected both rules to apply: %+v", entries[0])
+	}
+	if entries[1].Issue != "Standup" || entries[1].Fields != nil {
+		t.Errorf("expected entry outside of the time window to be kept: %+v", entries[1])
+	}
+	if entries[2].Issue != "INT-1" {
+		t.Errorf("expected the project to be matched: %+v", entries[2])
+	}
+
+	rules.Mode = "first"
+	entries, _ = rules.TransformTimeEntries([]TimeEntry{{Issue: "Standup", From: monday, Attributes: map[string]string{"description": "Standup"}}})
+	if entries[0].Issue != "OPS-1" || entries[0].Fields != nil {
+		t.Errorf("expected only the first rule to apply: %+v", entries[0])
+	}
+}
@@ -1,67 +1,81 @@
 package entries
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"gopkg.in/yaml.v3"
+
 	"github.com/tornermarton/timesheets/internal/arrays"
+	"github.com/tornermarton/timesheets/internal/httpclient"
 	"github.com/tornermarton/timesheets/internal/utils"
 )
 
 type TimeEntrySource interface {
-	PullTimeEntries(from time.Time, till time.Time) ([]TimeEntry, error)
+	PullTimeEntries(ctx context.Context, from time.Time, till time.Time) ([]TimeEntry, error)
 }
 
 type TogglTrackDefaults struct {
-	Description string
+	Description string `yaml:"description"`
 }
 type TogglTrack struct {
 	Workspace int
 
-	Token   string
-	Url     url.URL
-	Timeout time.Duration
-	Ca      *string
+	// Issue lists the strategies (description, project, projects) used in order to determine the issue
+	Issue    []string
+	Projects map[string]string
+
+	Token  string
+	Url    url.URL
+	Client *httpclient.Client
 
 	Defaults TogglTrackDefaults
 }
 
 type togglTrackEntry struct {
+	Id          int64    `json:"id"`
 	Workspace   int      `json:"workspace_id"`
 	Start       string   `json:"start"`
 	Stop        *string  `json:"stop"`
 	Duration    float64  `json:"duration"`
 	Description string   `json:"description"`
 	Tags        []string `json:"tags"`
+	Billable    bool     `json:"billable"`
+
+	// Only present when requested with meta=true
+	ProjectId   *int64  `json:"project_id"`
+	ProjectName *string `json:"project_name"`
+	ClientName  *string `json:"client_name"`
+	TaskId      *int64  `json:"task_id"`
+	TaskName    *string `json:"task_name"`
 }
 
-func (t *TogglTrack) getEntries(from time.Time, till time.Time) ([]togglTrackEntry, error) {
-	client, err := utils.CreateHttpClient(t.Timeout, t.Ca)
-	if err != nil {
-		return nil, err
-	}
-
+func (t *TogglTrack) getEntries(ctx context.Context, from time.Time, till time.Time) ([]togglTrackEntry, error) {
 	fromStr := url.QueryEscape(from.Format(time.RFC3339))
 	tillStr := url.QueryEscape(till.Format(time.RFC3339))
-	reference, err := url.Parse(fmt.Sprintf("/api/v9/me/time_entries?start_date=%s&end_date=%s", fromStr, tillStr))
+	reference, err := url.Parse(fmt.Sprintf("/api/v9/me/time_entries?start_date=%s&end_date=%s&meta=true", fromStr, tillStr))
 	if err != nil {
 		return nil, err
 	}
 
-	request, err := http.NewRequest("GET", t.Url.ResolveReference(reference).String(), nil)
+	request, err := http.NewRequestWithContext(ctx, "GET", t.Url.ResolveReference(reference).String(), nil)
 	if err != nil {
 		return nil, err
 	}
 
 	request.SetBasicAuth(t.Token, "api_token")
 
-	response, err := client.Do(request)
+	response, err := t.Client.Do(request)
 	if err != nil {
 		return nil, err
 	}
@@ -84,37 +98,158 @@ func (t *TogglTrack) getEntries(from time.Time, till time.Time) ([]togglTrackEnt
 	return togglTrackEntries, nil
 }
 
-func (t *TogglTrack) convertEntry(entry togglTrackEntry) (TimeEntry, error) {
-	re := regexp.MustCompile(`\[([A-Za-z\d\-]+)]`)
-	match := re.FindStringSubmatch(entry.Description)
+type TogglTrackWorkspace struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (t *TogglTrack) getWorkspaces(ctx context.Context) ([]TogglTrackWorkspace, error) {
+	reference, err := url.Parse("/api/v9/me/workspaces")
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", t.Url.ResolveReference(reference).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.SetBasicAuth(t.Token, "api_token")
+
+	response, err := t.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, fmt.Errorf("cannot get TogglTrack workspaces (%d)", response.StatusCode)
+	}
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var workspaces []TogglTrackWorkspace
+	if err := json.Unmarshal(responseBody, &workspaces); err != nil {
+		return nil, err
+	}
+
+	return workspaces, nil
+}
+
+// ListTogglTrackWorkspaces returns the workspaces the token of the spec has access to, so one of them can be
+// picked before creating the source (the workspace of the spec is ignored).
+func ListTogglTrackWorkspaces(ctx context.Context, spec TogglTrackSpec) ([]TogglTrackWorkspace, error) {
+	url_, err := url.Parse(spec.Url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'url' spec for TogglTrack source: %w", err)
+	}
+
+	client, err := httpclient.NewFromSpec(spec.Spec, "TogglTrack source")
+	if err != nil {
+		return nil, err
+	}
+
+	toggl := &TogglTrack{Token: spec.Token, Url: *url_, Client: client}
+
+	return toggl.getWorkspaces(ctx)
+}
+
+var issuePattern = regexp.MustCompile(`\[([A-Za-z\d\-]+)]`)
+
+// matchIssue extracts the issue from a "[ISSUE-123] description" formatted text.
+func matchIssue(text string) (string, string, bool) {
+	match := issuePattern.FindStringSubmatch(text)
+	if len(match) > 1 {
+		return match[1], strings.TrimSpace(strings.Replace(text, "["+match[1]+"]", "", 1)), true
+	}
+
+	return "", text, false
+}
+
+// parseDescription extracts the issue from a "[ISSUE-123] description" formatted text,
+// texts without a bracketed issue are used as the issue itself.
+func parseDescription(text string, fallback string) (string, string) {
+	if issue, description, ok := matchIssue(text); ok {
+		return issue, description
+	}
+
+	return text, fallback
+}
 
+// resolveIssue applies the configured strategies in order, the first one yielding an issue wins.
+// Without any match the whole description is used as the issue.
+func (t *TogglTrack) resolveIssue(entry togglTrackEntry) (string, string) {
+	_, description, _ := matchIssue(entry.Description)
+	description = utils.DefaultString(description, t.Defaults.Description)
+
+	for _, strategy := range t.Issue {
+		switch strategy {
+		case "description":
+			if issue, _, ok := matchIssue(entry.Description); ok {
+				return issue, description
+			}
+		case "project":
+			if entry.ProjectName != nil && *entry.ProjectName != "" {
+				issue, _ := parseDescription(*entry.ProjectName, "")
+				return issue, description
+			}
+		case "projects":
+			if entry.ProjectName != nil {
+				if issue, ok := t.Projects[*entry.ProjectName]; ok {
+					return issue, description
+				}
+			}
+		}
+	}
+
+	return parseDescription(entry.Description, t.Defaults.Description)
+}
+
+func (t *TogglTrack) convertEntry(entry togglTrackEntry) (TimeEntry, error) {
 	from, err := time.Parse(time.RFC3339, entry.Start)
 	if err != nil {
 		return TimeEntry{}, err
 	}
 	till := from.Add(time.Duration(entry.Duration) * time.Second)
 
-	var issue string
-	var description string
-	if len(match) > 1 {
-		issue = match[1]
-		description = strings.TrimSpace(strings.Replace(entry.Description, "["+issue+"]", "", 1))
-	} else {
-		issue = entry.Description
-		description = t.Defaults.Description
+	issue, description := t.resolveIssue(entry)
+
+	attributes := map[string]string{
+		"description": entry.Description,
+		"billable":    strconv.FormatBool(entry.Billable),
+	}
+	if entry.ProjectId != nil {
+		attributes["project_id"] = strconv.FormatInt(*entry.ProjectId, 10)
+	}
+	if entry.ProjectName != nil {
+		attributes["project"] = *entry.ProjectName
+	}
+	if entry.ClientName != nil {
+		attributes["client"] = *entry.ClientName
+	}
+	if entry.TaskId != nil {
+		attributes["task_id"] = strconv.FormatInt(*entry.TaskId, 10)
+	}
+	if entry.TaskName != nil {
+		attributes["task"] = *entry.TaskName
 	}
 
 	return TimeEntry{
+		Id:          fmt.Sprintf("TogglTrack/%d", entry.Id),
 		Issue:       issue,
 		From:        from,
 		Till:        till,
 		Description: description,
 		Tags:        entry.Tags,
+		Attributes:  attributes,
 	}, nil
 }
 
-func (t *TogglTrack) PullTimeEntries(from time.Time, till time.Time) ([]TimeEntry, error) {
-	entries, err := t.getEntries(from, till)
+func (t *TogglTrack) PullTimeEntries(ctx context.Context, from time.Time, till time.Time) ([]TimeEntry, error) {
+	entries, err := t.getEntries(ctx, from, till)
 	if err != nil {
 		return nil, err
 	}
@@ -124,81 +259,375 @@ func (t *TogglTrack) PullTimeEntries(from time.Time, till time.Time) ([]TimeEntr
 	return arrays.MapE(entries, func(entry togglTrackEntry) (TimeEntry, error) { return t.convertEntry(entry) })
 }
 
-func createTogglTrack(spec map[string]any) (*TogglTrack, error) {
-	var workspace int
-	if workspaceParam, ok := spec["workspace"].(int); ok {
-		workspace = workspaceParam
-	} else {
+type TogglTrackSpec struct {
+	Workspace int    `yaml:"workspace" schema:"required"`
+	Token     string `yaml:"token" schema:"required"`
+	Url       string `yaml:"url"`
+
+	httpclient.Spec `yaml:",inline"`
+
+	// Issue lists the strategies (description, project, projects) used in order to determine the issue
+	Issue    []string          `yaml:"issue" schema:"enum=description|project|projects"`
+	Projects map[string]string `yaml:"projects,omitempty"`
+
+	Defaults TogglTrackDefaults `yaml:"defaults"`
+}
+
+func defaultTogglTrackSpec() TogglTrackSpec {
+	return TogglTrackSpec{
+		Url:   "https://api.track.toggl.com",
+		Spec:  httpclient.DefaultSpec(),
+		Issue: []string{"description"},
+	}
+}
+
+func createTogglTrack(spec TogglTrackSpec) (*TogglTrack, error) {
+	if spec.Workspace == 0 {
 		return nil, fmt.Errorf("invalid or missing 'workspace' spec for TogglTrack source")
 	}
 
-	var token string
-	if tokenParam, ok := spec["token"].(string); ok && tokenParam != "" {
-		token = tokenParam
-	} else {
+	if spec.Token == "" {
 		return nil, fmt.Errorf("invalid or missing 'token' spec for TogglTrack source")
 	}
 
-	var url = url.URL{
-		Scheme: "https",
-		Host:   "api.track.toggl.com",
+	url_, err := url.Parse(spec.Url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'url' spec for TogglTrack source: %w", err)
 	}
-	if urlParam, ok := spec["url"].(string); ok {
-		url_, err := url.Parse(urlParam)
-		if err != nil {
-			return nil, fmt.Errorf("invalid 'url' spec for TogglTrack source: %w", err)
+
+	client, err := httpclient.NewFromSpec(spec.Spec, "TogglTrack source")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, strategy := range spec.Issue {
+		if strategy != "description" && strategy != "project" && strategy != "projects" {
+			return nil, fmt.Errorf("invalid 'issue' spec for TogglTrack source: %v (expected description, project or projects)", strategy)
 		}
-		url = *url_
 	}
 
-	var timeout = 10 * time.Second
-	if timeoutParam, ok := spec["timeout"].(string); ok {
-		timeout_, err := time.ParseDuration(timeoutParam)
-		if err != nil {
-			return nil, fmt.Errorf("invalid 'timeout' spec for TogglTrack source: %w", err)
+	return &TogglTrack{
+		Workspace: spec.Workspace,
+
+		Issue:    spec.Issue,
+		Projects: spec.Projects,
+
+		Token:  spec.Token,
+		Url:    *url_,
+		Client: client,
+
+		Defaults: spec.Defaults,
+	}, nil
+}
+
+type ClockifyDefaults struct {
+	Description string `yaml:"description"`
+}
+type Clockify struct {
+	Workspace string
+	User      *string
+
+	Token  string
+	Url    url.URL
+	Client *httpclient.Client
+
+	Defaults ClockifyDefaults
+}
+
+type clockifyUser struct {
+	Id string `json:"id"`
+}
+type clockifyTag struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+type clockifyEntryInterval struct {
+	Start string  `json:"start"`
+	End   *string `json:"end"`
+}
+type clockifyEntry struct {
+	Id           string                `json:"id"`
+	Description  string                `json:"description"`
+	TagIds       []string              `json:"tagIds"`
+	TimeInterval clockifyEntryInterval `json:"timeInterval"`
+}
+
+// Clockify paginates every listing endpoint, pages are requested until a partial page is returned
+const clockifyPageSize = 200
+
+func (c *Clockify) get(ctx context.Context, path string, out any) error {
+	reference, err := url.Parse(path)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", c.Url.ResolveReference(reference).String(), nil)
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("X-Api-Key", c.Token)
+
+	response, err := c.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("cannot get Clockify %s (%d)", reference.Path, response.StatusCode)
+	}
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(responseBody, out)
+}
+
+func (c *Clockify) getUser(ctx context.Context) (string, error) {
+	if c.User != nil {
+		return *c.User, nil
+	}
+
+	var user clockifyUser
+	if err := c.get(ctx, "/api/v1/user", &user); err != nil {
+		return "", err
+	}
+
+	return user.Id, nil
+}
+
+func (c *Clockify) Verify(ctx context.Context) error {
+	var user clockifyUser
+	return c.get(ctx, "/api/v1/user", &user)
+}
+
+// getTags returns the names of the tags by their id, including the archived ones still used by older entries.
+func (c *Clockify) getTags(ctx context.Context) (map[string]string, error) {
+	tags := map[string]string{}
+
+	for _, archived := range []bool{false, true} {
+		for page := 1; ; page++ {
+			var clockifyTags []clockifyTag
+			if err := c.get(ctx, fmt.Sprintf("/api/v1/workspaces/%s/tags?archived=%t&page=%d&page-size=%d", url.PathEscape(c.Workspace), archived, page, clockifyPageSize), &clockifyTags); err != nil {
+				return nil, err
+			}
+
+			for _, tag := range clockifyTags {
+				tags[tag.Id] = tag.Name
+			}
+
+			if len(clockifyTags) < clockifyPageSize {
+				break
+			}
 		}
-		timeout = timeout_
 	}
 
-	var ca *string = nil
-	if caParam, ok := spec["ca"].(string); ok {
-		ca = &caParam
+	return tags, nil
+}
+
+func (c *Clockify) getEntries(ctx context.Context, from time.Time, till time.Time) ([]clockifyEntry, error) {
+	user, err := c.getUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fromStr := url.QueryEscape(from.UTC().Format(time.RFC3339))
+	tillStr := url.QueryEscape(till.UTC().Format(time.RFC3339))
+
+	var entries []clockifyEntry
+	for page := 1; ; page++ {
+		var clockifyEntries []clockifyEntry
+		if err := c.get(ctx, fmt.Sprintf("/api/v1/workspaces/%s/user/%s/time-entries?start=%s&end=%s&in-progress=false&page=%d&page-size=%d", url.PathEscape(c.Workspace), url.PathEscape(user), fromStr, tillStr, page, clockifyPageSize), &clockifyEntries); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, clockifyEntries...)
+
+		if len(clockifyEntries) < clockifyPageSize {
+			return entries, nil
+		}
 	}
+}
 
-	var defaults = map[string]any{}
-	if defaultsParam, ok := spec["defaults"].(map[string]any); ok {
-		defaults = defaultsParam
+func (c *Clockify) convertEntry(entry clockifyEntry, tags map[string]string) (TimeEntry, error) {
+	from, err := time.Parse(time.RFC3339, entry.TimeInterval.Start)
+	if err != nil {
+		return TimeEntry{}, err
 	}
 
-	var description = ""
-	if descriptionParam, ok := defaults["description"].(string); ok {
-		description = descriptionParam
+	till, err := time.Parse(time.RFC3339, *entry.TimeInterval.End)
+	if err != nil {
+		return TimeEntry{}, err
 	}
 
-	return &TogglTrack{
-		Workspace: workspace,
+	issue, description := parseDescription(entry.Description, c.Defaults.Description)
 
-		Token:   token,
-		Url:     url,
-		Timeout: timeout,
-		Ca:      ca,
+	// Deleted tags are no longer listed, they are left out (see PullTimeEntries)
+	var names []string
+	for _, id := range entry.TagIds {
+		if name, ok := tags[id]; ok {
+			names = append(names, name)
+		}
+	}
 
-		Defaults: TogglTrackDefaults{
-			Description: description,
+	return TimeEntry{
+		Id:          fmt.Sprintf("Clockify/%s", entry.Id),
+		Issue:       issue,
+		From:        from,
+		Till:        till,
+		Description: description,
+		Tags:        names,
+		Attributes: map[string]string{
+			"description": entry.Description,
 		},
 	}, nil
 }
 
+func (c *Clockify) PullTimeEntries(ctx context.Context, from time.Time, till time.Time) ([]TimeEntry, error) {
+	tags, err := c.getTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := c.getEntries(ctx, from, till)
+	if err != nil {
+		return nil, err
+	}
+
+	entries = arrays.Filter(entries, func(entry clockifyEntry) bool { return entry.TimeInterval.End != nil })
+
+	// A single deleted tag should not stop the whole pull, so it is only warned about (once)
+	unknown := map[string]bool{}
+	for _, entry := range entries {
+		for _, id := range entry.TagIds {
+			if _, ok := tags[id]; !ok && !unknown[id] {
+				unknown[id] = true
+				log.Printf("warning: skipping unknown (deleted) Clockify tag %s of entry %s\n", id, entry.Id)
+			}
+		}
+	}
+
+	return arrays.MapE(entries, func(entry clockifyEntry) (TimeEntry, error) { return c.convertEntry(entry, tags) })
+}
+
+type ClockifySpec struct {
+	Workspace string  `yaml:"workspace" schema:"required"`
+	User      *string `yaml:"user,omitempty"`
+	Token     string  `yaml:"token" schema:"required"`
+	Url       string  `yaml:"url"`
+
+	httpclient.Spec `yaml:",inline"`
+
+	Defaults ClockifyDefaults `yaml:"defaults"`
+}
+
+func defaultClockifySpec() ClockifySpec {
+	return ClockifySpec{
+		Url:  "https://api.clockify.me",
+		Spec: httpclient.DefaultSpec(),
+	}
+}
+
+func createClockify(spec ClockifySpec) (*Clockify, error) {
+	if spec.Workspace == "" {
+		return nil, fmt.Errorf("invalid or missing 'workspace' spec for Clockify source")
+	}
+
+	if spec.Token == "" {
+		return nil, fmt.Errorf("invalid or missing 'token' spec for Clockify source")
+	}
+
+	url_, err := url.Parse(spec.Url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'url' spec for Clockify source: %w", err)
+	}
+
+	client, err := httpclient.NewFromSpec(spec.Spec, "Clockify source")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Clockify{
+		Workspace: spec.Workspace,
+		User:      spec.User,
+
+		Token:  spec.Token,
+		Url:    *url_,
+		Client: client,
+
+		Defaults: spec.Defaults,
+	}, nil
+}
+
 type TimeEntrySourceConfig struct {
-	Kind string         `yaml:"kind"`
-	Spec map[string]any `yaml:"spec"`
+	Name string `yaml:"name,omitempty"`
+	Kind string `yaml:"kind" schema:"required"`
+	// Spec is decoded once the kind is known, into the spec of the kind (e.g. TogglTrackSpec)
+	Spec yaml.Node `yaml:"spec,omitempty"`
+}
+
+type namedTimeEntrySource struct {
+	name   string
+	source TimeEntrySource
+}
+
+// MergedTimeEntrySource pulls from every source and merges their entries chronologically.
+type MergedTimeEntrySource struct {
+	sources []namedTimeEntrySource
+}
+
+func (m *MergedTimeEntrySource) PullTimeEntries(ctx context.Context, from time.Time, till time.Time) ([]TimeEntry, error) {
+	var entries []TimeEntry
+	for _, source := range m.sources {
+		entries_, err := source.source.PullTimeEntries(ctx, from, till)
+		if err != nil {
+			return nil, fmt.Errorf("cannot pull entries of source %s: %s", source.name, err)
+		}
+
+		for _, entry := range entries_ {
+			entry.Source = source.name
+			entries = append(entries, entry)
+		}
+	}
+
+	slices.SortStableFunc(entries, func(a TimeEntry, b TimeEntry) int { return a.From.Compare(b.From) })
+
+	return entries, nil
 }
 
 func NewTimeEntrySource(config TimeEntrySourceConfig) (TimeEntrySource, error) {
-	switch config.Kind {
-	case "TogglTrack":
-		return createTogglTrack(config.Spec)
-	default:
-		return nil, fmt.Errorf("unsupported time entry source: %s", config.Kind)
+	return create(TimeEntrySourceKinds, "source", config.Kind, &config.Spec, environment{})
+}
+
+// ResolveTimeEntrySourceConfig returns the config with the defaults of the source applied to its spec.
+func ResolveTimeEntrySourceConfig(config TimeEntrySourceConfig) (TimeEntrySourceConfig, error) {
+	spec, err := resolve(TimeEntrySourceKinds, "source", config.Kind, &config.Spec, environment{})
+	if err != nil {
+		return config, err
+	}
+
+	config.Spec = spec
+
+	return config, nil
+}
+
+func NewTimeEntrySources(configs []TimeEntrySourceConfig) (*MergedTimeEntrySource, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no time entry source configured")
 	}
+
+	merged := &MergedTimeEntrySource{}
+	for _, config := range configs {
+		source, err := NewTimeEntrySource(config)
+		if err != nil {
+			return nil, err
+		}
+
+		merged.sources = append(merged.sources, namedTimeEntrySource{name: utils.DefaultString(config.Name, config.Kind), source: source})
+	}
+
+	return merged, nil
 }
@@ -0,0 +1,169 @@
+package entries
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tornermarton/timesheets/internal/httpclient"
+	"github.com/tornermarton/timesheets/internal/utils"
+)
+
+// newTestClient creates a client failing fast, without retries
+func newTestClient() *httpclient.Client {
+	client, _ := httpclient.New(httpclient.Options{Timeout: time.Second})
+	return client
+}
+
+func TestParseDescription(t *testing.T) {
+	cases := []struct {
+		text        string
+		issue       string
+		description string
+	}{
+		{"[ABC-123] Fix login", "ABC-123", "Fix login"},
+		{"Fix login [ABC-123]", "ABC-123", "Fix login"},
+		{"[ABC-123]", "ABC-123", ""},
+		{"ABC-123", "ABC-123", "fallback"},
+	}
+
+	for _, c := range cases {
+		issue, description := parseDescription(c.text, "fallback")
+		if issue != c.issue || description != c.description {
+			t.Errorf("parseDescription(%q) = %q, %q; want %q, %q", c.text, issue, description, c.issue, c.description)
+		}
+	}
+}
+
+func TestClockifyPullTimeEntries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/api/v1/user":
+			w.Write([]byte(`{"id": "u1"}`))
+		case "/api/v1/workspaces/w1/tags":
+			if r.URL.Query().Get("archived") == "true" {
+				w.Write([]byte(`[{"id": "t3", "name": "legacy"}]`))
+				return
+			}
+			w.Write([]byte(`[{"id": "t1", "name": "meeting"}, {"id": "t2", "name": "home-office"}]`))
+		case "/api/v1/workspaces/w1/user/u1/time-entries":
+			w.Write([]byte(`[
+				{"id": "e1", "description": "[ABC-1] Standup", "tagIds": ["t1", "t2", "t3", "deleted"], "timeInterval": {"start": "2025-06-01T08:00:00Z", "end": "2025-06-01T08:15:00Z"}},
+				{"id": "e2", "description": "[ABC-2] Running", "tagIds": [], "timeInterval": {"start": "2025-06-01T09:00:00Z", "end": null}}
+			]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	url_, _ := url.Parse(server.URL)
+	clockify := &Clockify{Workspace: "w1", Token: "secret", Url: *url_, Client: newTestClient()}
+
+	entries, err := clockify.PullTimeEntries(context.Background(), time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("expected only the completed entry, got %d", len(entries))
+	}
+
+	entry := entries[0]
+	if entry.Id != "Clockify/e1" || entry.Issue != "ABC-1" || entry.Description != "Standup" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if entry.Till.Sub(entry.From) != 15*time.Minute {
+		t.Errorf("unexpected duration: %s", entry.Till.Sub(entry.From))
+	}
+	// Archived tags are named too, deleted ones are left out
+	if !slices.Equal(entry.Tags, []string{"meeting", "home-office", "legacy"}) {
+		t.Errorf("unexpected tags: %v", entry.Tags)
+	}
+}
+
+func TestTogglTrackResolveIssue(t *testing.T) {
+	project := func(name string) *string { return &name }
+
+	toggl := &TogglTrack{
+		Issue:    []string{"projects", "description", "project"},
+		Projects: map[string]string{"Website": "WEB-1"},
+		Defaults: TogglTrackDefaults{Description: "Development"},
+	}
+
+	cases := []struct {
+		entry       togglTrackEntry
+		issue       string
+		description string
+	}{
+		{togglTrackEntry{Description: "[ABC-1] Fix login", ProjectName: project("Website")}, "WEB-1", "Fix login"},
+		{togglTrackEntry{Description: "[ABC-1] Fix login", ProjectName: project("[EPIC-7] Accounts")}, "ABC-1", "Fix login"},
+		{togglTrackEntry{Description: "Fix login", ProjectName: project("[EPIC-7] Accounts")}, "EPIC-7", "Fix login"},
+		{togglTrackEntry{Description: "", ProjectName: project("EPIC-8")}, "EPIC-8", "Development"},
+		{togglTrackEntry{Description: "ABC-2"}, "ABC-2", "Development"},
+	}
+
+	for _, c := range cases {
+		issue, description := toggl.resolveIssue(c.entry)
+		if issue != c.issue || description != c.description {
+			t.Errorf("resolveIssue(%+v) = %q, %q; want %q, %q", c.entry, issue, description, c.issue, c.description)
+		}
+	}
+}
+
+func TestListTogglTrackWorkspaces(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, password, ok := r.BasicAuth(); !ok || token != "secret" || password != "api_token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if r.URL.Path == "/api/v9/me/workspaces" {
+			w.Write([]byte(`[{"id": 6365718, "name": "Acme"}, {"id": 42, "name": "Personal"}]`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	spec := defaultTogglTrackSpec()
+	spec.Url, spec.Token, spec.Retries = server.URL, "secret", 0
+
+	workspaces, err := ListTogglTrackWorkspaces(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !slices.Equal(workspaces, []TogglTrackWorkspace{{Id: 6365718, Name: "Acme"}, {Id: 42, Name: "Personal"}}) {
+		t.Errorf("unexpected workspaces: %v", workspaces)
+	}
+
+	spec.Token = "wrong"
+	if _, err := ListTogglTrackWorkspaces(context.Background(), spec); err == nil {
+		t.Errorf("expected an invalid token to be rejected")
+	}
+}
+
+type failingSource struct{}
+
+func (failingSource) PullTimeEntries(ctx context.Context, from time.Time, till time.Time) ([]TimeEntry, error) {
+	return nil, fmt.Errorf("cannot get TogglTrack entries (%d)", http.StatusUnauthorized)
+}
+
+func TestMergedPullTimeEntriesError(t *testing.T) {
+	merged := &MergedTimeEntrySource{sources: []namedTimeEntrySource{{name: "Toggl", source: failingSource{}}}}
+
+	_, err := merged.PullTimeEntries(context.Background(), time.Time{}, time.Time{})
+	if message := utils.GetErrorMessage(err); message != "cannot pull entries of source Toggl: cannot get TogglTrack entries (401)" {
+		t.Errorf("expected the error of the source to be reported once, got %q", message)
+	}
+}
@@ -2,39 +2,64 @@ package entries
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"maps"
 	"math"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"gopkg.in/yaml.v3"
+
+	"github.com/tornermarton/timesheets/internal/arrays"
+	"github.com/tornermarton/timesheets/internal/cache"
+	"github.com/tornermarton/timesheets/internal/httpclient"
 	"github.com/tornermarton/timesheets/internal/utils"
 )
 
 type TimeEntryTarget interface {
-	PushTimeEntry(entry TimeEntry) error
+	// PushTimeEntry creates the entry and returns the identifier of the created worklog.
+	PushTimeEntry(ctx context.Context, entry TimeEntry) (string, error)
+	// UpdateTimeEntry overwrites the worklog previously created by PushTimeEntry and returns the identifier
+	// of the updated worklog, which differs from the given one if the worklog had to be recreated.
+	UpdateTimeEntry(ctx context.Context, worklog string, entry TimeEntry) (string, error)
+	// DeleteTimeEntry removes the worklog previously created by PushTimeEntry.
+	DeleteTimeEntry(ctx context.Context, worklog string) error
+	// ListTimeEntries reads back the worklogs starting within [from, till), their Id is the worklog identifier.
+	ListTimeEntries(ctx context.Context, from time.Time, till time.Time) ([]TimeEntry, error)
+	// MapTimeEntry returns the entry as the target would store it, so it can be compared with listed worklogs.
+	MapTimeEntry(entry TimeEntry) TimeEntry
+	// PreviewTimeEntry returns the payload PushTimeEntry and UpdateTimeEntry send for the entry.
+	PreviewTimeEntry(ctx context.Context, entry TimeEntry) (any, error)
 }
 
 type CapsysKronosTags map[string]map[string]any
 type CapsysKronosDefaults struct {
-	ActivityCategoryId int
-	ActivityTypeId     int
-	SiteId             int
-	Comment            string
+	ActivityCategoryId int    `yaml:"activityCategoryId"`
+	ActivityTypeId     int    `yaml:"activityTypeId"`
+	SiteId             int    `yaml:"siteId"`
+	Comment            string `yaml:"comment"`
 }
 type CapsysKronos struct {
-	Token   string
-	Url     url.URL
-	Timeout time.Duration
-	Ca      *string
+	Token  string
+	Url    url.URL
+	Client *httpclient.Client
+
+	// Kronos is unaware of timezones, worklogs are stored in the local time of this location
+	Location *time.Location
 
 	Tags CapsysKronosTags
 
 	Defaults CapsysKronosDefaults
+
+	issues issueCache[bool]
 }
 
 type capsysKronosTimeEntryWorklogInput struct {
@@ -55,15 +80,16 @@ type capsysKronosTimeEntry struct {
 	WorklogInput capsysKronosTimeEntryWorklogInput `json:"worklogInput"`
 	TravelInput  capsysKronosTimeEntryTravelInput  `json:"travelInput"`
 }
+type capsysKronosLogEntry struct {
+	Id json.Number `json:"id"`
+	capsysKronosTimeEntryWorklogInput
+}
 
 func (c *CapsysKronos) convertEntry(entry TimeEntry) (capsysKronosTimeEntry, error) {
-	// Kronos is unaware of timezones so it must be converted to its default
-	tz, _ := time.LoadLocation("Europe/Budapest")
-
 	worklogInput := capsysKronosTimeEntryWorklogInput{
 		IssueKey:            entry.Issue,
 		TimeSpent:           math.Floor(entry.Till.Sub(entry.From).Minutes()),
-		StartOffsetDateTime: entry.From.Truncate(time.Minute).In(tz).Format(time.RFC3339),
+		StartOffsetDateTime: entry.From.Truncate(time.Minute).In(c.Location).Format(time.RFC3339),
 		Comment:             utils.DefaultString(entry.Description, c.Defaults.Comment),
 		ActivityCategoryId:  c.Defaults.ActivityCategoryId,
 		ActivityTypeId:      c.Defaults.ActivityTypeId,
@@ -83,6 +109,7 @@ func (c *CapsysKronos) convertEntry(entry TimeEntry) (capsysKronosTimeEntry, err
 			maps.Copy(worklogInputData, tagData)
 		}
 	}
+	maps.Copy(worklogInputData, entry.Fields)
 	b, _ = json.Marshal(worklogInputData)
 	_ = json.Unmarshal(b, &worklogInput)
 
@@ -92,45 +119,168 @@ func (c *CapsysKronos) convertEntry(entry TimeEntry) (capsysKronosTimeEntry, err
 	}, nil
 }
 
-func (c *CapsysKronos) validateTimeEntryIssue(entry TimeEntry) error {
-	client, err := utils.CreateHttpClient(c.Timeout, c.Ca)
+func (c *CapsysKronos) convertWorklog(worklog capsysKronosTimeEntryWorklogInput) (TimeEntry, error) {
+	from, err := time.Parse(time.RFC3339, worklog.StartOffsetDateTime)
 	if err != nil {
-		return err
+		return TimeEntry{}, err
+	}
+
+	return TimeEntry{
+		Issue:       worklog.IssueKey,
+		From:        from,
+		Till:        from.Add(time.Duration(worklog.TimeSpent) * time.Minute),
+		Description: worklog.Comment,
+		Fields: map[string]any{
+			"activityCategoryId": worklog.ActivityCategoryId,
+			"activityTypeId":     worklog.ActivityTypeId,
+			"siteId":             worklog.SiteId,
+		},
+	}, nil
+}
+
+func (c *CapsysKronos) getIssue(ctx context.Context, issue string) (bool, error) {
+	reference, err := url.Parse(fmt.Sprintf("/rest/api/latest/issue/%s", issue))
+	if err != nil {
+		return false, err
 	}
 
-	reference, err := url.Parse(fmt.Sprintf("/rest/api/latest/issue/%s", entry.Issue))
+	request, err := http.NewRequestWithContext(ctx, "GET", c.Url.ResolveReference(reference).String(), nil)
+	if err != nil {
+		return false, err
+	}
+
+	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
+
+	response, err := c.Client.Do(request)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return false, issueNotFound{fmt.Errorf("invalid CapsysKronos issue (%d)", response.StatusCode)}
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return false, fmt.Errorf("cannot get CapsysKronos issue %s (%d)", issue, response.StatusCode)
+	}
+
+	return true, nil
+}
+
+func (c *CapsysKronos) Verify(ctx context.Context) error {
+	reference, err := url.Parse("/rest/api/latest/myself")
 	if err != nil {
 		return err
 	}
 
-	request, err := http.NewRequest("GET", c.Url.ResolveReference(reference).String(), nil)
+	request, err := http.NewRequestWithContext(ctx, "GET", c.Url.ResolveReference(reference).String(), nil)
 	if err != nil {
 		return err
 	}
 
 	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
 
-	response, err := client.Do(request)
+	response, err := c.Client.Do(request)
 	if err != nil {
 		return err
 	}
 	defer response.Body.Close()
 
 	if response.StatusCode < 200 || response.StatusCode >= 300 {
-		return fmt.Errorf("invalid CapsysKronos issue (%d)", response.StatusCode)
+		return fmt.Errorf("cannot verify CapsysKronos token (%d)", response.StatusCode)
 	}
 
 	return nil
+}
 
+func (c *CapsysKronos) validateTimeEntryIssue(ctx context.Context, entry TimeEntry) error {
+	_, err := c.issues.resolve(ctx, entry.Issue, c.getIssue)
+	return err
 }
 
-func (c *CapsysKronos) postEntry(entry capsysKronosTimeEntry) error {
-	client, err := utils.CreateHttpClient(c.Timeout, c.Ca)
+func (c *CapsysKronos) postEntry(ctx context.Context, entry capsysKronosTimeEntry) (string, error) {
+	reference, err := url.Parse("/rest/kronos/1.0/log-entry")
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	reference, err := url.Parse("/rest/kronos/1.0/log-entry")
+	requestBody, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", c.Url.ResolveReference(reference).String(), bytes.NewBuffer(requestBody))
+	if err != nil {
+		return "", err
+	}
+
+	request.Header.Set("Authorization", "Bearer "+c.Token)
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := c.Client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return "", fmt.Errorf("could not create CapsysKronos entry (%d): %s", response.StatusCode, strings.ReplaceAll(string(responseBody), "\n", ""))
+	}
+
+	var logEntry capsysKronosLogEntry
+	if err := json.Unmarshal(responseBody, &logEntry); err != nil {
+		return "", fmt.Errorf("could not read created CapsysKronos entry: %w", err)
+	}
+
+	return logEntry.Id.String(), nil
+}
+
+func (c *CapsysKronos) getEntries(ctx context.Context, from time.Time, till time.Time) ([]capsysKronosLogEntry, error) {
+	// Kronos filters by whole days, the exact window is applied by the caller
+	fromStr := url.QueryEscape(from.Format(time.DateOnly))
+	tillStr := url.QueryEscape(till.Format(time.DateOnly))
+	reference, err := url.Parse(fmt.Sprintf("/rest/kronos/1.0/log-entry?from=%s&to=%s", fromStr, tillStr))
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", c.Url.ResolveReference(reference).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Authorization", "Bearer "+c.Token)
+
+	response, err := c.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, fmt.Errorf("cannot get CapsysKronos entries (%d)", response.StatusCode)
+	}
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var logEntries []capsysKronosLogEntry
+	if err := json.Unmarshal(responseBody, &logEntries); err != nil {
+		return nil, err
+	}
+
+	return logEntries, nil
+}
+
+func (c *CapsysKronos) putEntry(ctx context.Context, worklog string, entry capsysKronosTimeEntry) error {
+	reference, err := url.Parse(fmt.Sprintf("/rest/kronos/1.0/log-entry/%s", url.PathEscape(worklog)))
 	if err != nil {
 		return err
 	}
@@ -140,7 +290,7 @@ func (c *CapsysKronos) postEntry(entry capsysKronosTimeEntry) error {
 		return err
 	}
 
-	request, err := http.NewRequest("POST", c.Url.ResolveReference(reference).String(), bytes.NewBuffer(requestBody))
+	request, err := http.NewRequestWithContext(ctx, "PUT", c.Url.ResolveReference(reference).String(), bytes.NewBuffer(requestBody))
 	if err != nil {
 		return err
 	}
@@ -148,7 +298,7 @@ func (c *CapsysKronos) postEntry(entry capsysKronosTimeEntry) error {
 	request.Header.Set("Authorization", "Bearer "+c.Token)
 	request.Header.Set("Content-Type", "application/json")
 
-	response, err := client.Do(request)
+	response, err := c.Client.Do(request)
 	if err != nil {
 		return err
 	}
@@ -160,120 +310,984 @@ func (c *CapsysKronos) postEntry(entry capsysKronosTimeEntry) error {
 	}
 
 	if response.StatusCode < 200 || response.StatusCode >= 300 {
-		return fmt.Errorf("could not create CapsysKronos entry (%d): %s", response.StatusCode, strings.ReplaceAll(string(responseBody), "\n", ""))
+		return fmt.Errorf("could not update CapsysKronos entry %s (%d): %s", worklog, response.StatusCode, strings.ReplaceAll(string(responseBody), "\n", ""))
 	}
 
 	return nil
 }
 
-func (c *CapsysKronos) PushTimeEntry(entry TimeEntry) error {
-	if err := c.validateTimeEntryIssue(entry); err != nil {
+func (c *CapsysKronos) deleteEntry(ctx context.Context, worklog string) error {
+	reference, err := url.Parse(fmt.Sprintf("/rest/kronos/1.0/log-entry/%s", url.PathEscape(worklog)))
+	if err != nil {
 		return err
 	}
 
-	entry_, err := c.convertEntry(entry)
+	request, err := http.NewRequestWithContext(ctx, "DELETE", c.Url.ResolveReference(reference).String(), nil)
 	if err != nil {
 		return err
 	}
 
-	return c.postEntry(entry_)
+	request.Header.Set("Authorization", "Bearer "+c.Token)
+
+	response, err := c.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	// An already deleted worklog is the desired end state
+	if response.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("could not delete CapsysKronos entry %s (%d): %s", worklog, response.StatusCode, strings.ReplaceAll(string(responseBody), "\n", ""))
+	}
+
+	return nil
 }
 
-func createCapsysKronos(spec map[string]any) (*CapsysKronos, error) {
-	var token string
-	if tokenParam, ok := spec["token"].(string); ok && tokenParam != "" {
-		token = tokenParam
-	} else {
+func (c *CapsysKronos) PushTimeEntry(ctx context.Context, entry TimeEntry) (string, error) {
+	if err := c.validateTimeEntryIssue(ctx, entry); err != nil {
+		return "", err
+	}
+
+	entry_, err := c.convertEntry(entry)
+	if err != nil {
+		return "", err
+	}
+
+	return c.postEntry(ctx, entry_)
+}
+
+func (c *CapsysKronos) UpdateTimeEntry(ctx context.Context, worklog string, entry TimeEntry) (string, error) {
+	if err := c.validateTimeEntryIssue(ctx, entry); err != nil {
+		return "", err
+	}
+
+	entry_, err := c.convertEntry(entry)
+	if err != nil {
+		return "", err
+	}
+
+	return worklog, c.putEntry(ctx, worklog, entry_)
+}
+
+func (c *CapsysKronos) DeleteTimeEntry(ctx context.Context, worklog string) error {
+	return c.deleteEntry(ctx, worklog)
+}
+
+func (c *CapsysKronos) ListTimeEntries(ctx context.Context, from time.Time, till time.Time) ([]TimeEntry, error) {
+	logEntries, err := c.getEntries(ctx, from, till)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := arrays.MapE(logEntries, func(logEntry capsysKronosLogEntry) (TimeEntry, error) {
+		entry, err := c.convertWorklog(logEntry.capsysKronosTimeEntryWorklogInput)
+		entry.Id = logEntry.Id.String()
+		return entry, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return arrays.Filter(entries, func(entry TimeEntry) bool { return entry.StartsWithin(from, till) }), nil
+}
+
+func (c *CapsysKronos) MapTimeEntry(entry TimeEntry) TimeEntry {
+	entry_, _ := c.convertEntry(entry)
+
+	// The worklog input is produced by convertEntry itself, so it always parses
+	mapped, _ := c.convertWorklog(entry_.WorklogInput)
+	mapped.Id = entry.Id
+
+	return mapped
+}
+
+func (c *CapsysKronos) PreviewTimeEntry(ctx context.Context, entry TimeEntry) (any, error) {
+	return c.convertEntry(entry)
+}
+
+// CapsysKronosFields are the worklog fields tags can set, overriding the defaults.
+type CapsysKronosFields struct {
+	ActivityCategoryId *int    `yaml:"activityCategoryId,omitempty"`
+	ActivityTypeId     *int    `yaml:"activityTypeId,omitempty"`
+	SiteId             *int    `yaml:"siteId,omitempty"`
+	Comment            *string `yaml:"comment,omitempty"`
+
+	// Other holds any other field of the worklog, set as it is
+	Other map[string]any `yaml:",inline"`
+}
+
+func (f CapsysKronosFields) values() map[string]any {
+	values := maps.Clone(f.Other)
+	if values == nil {
+		values = map[string]any{}
+	}
+	if f.ActivityCategoryId != nil {
+		values["activityCategoryId"] = *f.ActivityCategoryId
+	}
+	if f.ActivityTypeId != nil {
+		values["activityTypeId"] = *f.ActivityTypeId
+	}
+	if f.SiteId != nil {
+		values["siteId"] = *f.SiteId
+	}
+	if f.Comment != nil {
+		values["comment"] = *f.Comment
+	}
+	return values
+}
+
+type CapsysKronosSpec struct {
+	Token string `yaml:"token" schema:"required"`
+	Url   string `yaml:"url"`
+
+	httpclient.Spec `yaml:",inline"`
+
+	// Timezone defaults to the global timezone
+	Timezone string `yaml:"timezone,omitempty"`
+
+	Tags map[string]CapsysKronosFields `yaml:"tags,omitempty"`
+
+	Defaults CapsysKronosDefaults `yaml:"defaults"`
+}
+
+func defaultCapsysKronosSpec() CapsysKronosSpec {
+	return CapsysKronosSpec{
+		Url:  "https://jira.capsys.hu",
+		Spec: httpclient.DefaultSpec(),
+		Defaults: CapsysKronosDefaults{
+			ActivityCategoryId: 3,
+			ActivityTypeId:     5,
+			SiteId:             31,
+		},
+	}
+}
+
+func createCapsysKronos(spec CapsysKronosSpec, location *time.Location, cache_ *cache.Cache) (*CapsysKronos, error) {
+	if spec.Token == "" {
 		return nil, fmt.Errorf("invalid or missing 'token' spec for CapsysKronos target")
 	}
 
-	var url = url.URL{
-		Scheme: "https",
-		Host:   "jira.capsys.hu",
+	url_, err := url.Parse(spec.Url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'url' spec for CapsysKronos target: %w", err)
+	}
+
+	client, err := httpclient.NewFromSpec(spec.Spec, "CapsysKronos target")
+	if err != nil {
+		return nil, err
 	}
-	if urlParam, ok := spec["url"].(string); ok {
-		url_, err := url.Parse(urlParam)
+
+	if spec.Timezone != "" {
+		location_, err := time.LoadLocation(spec.Timezone)
 		if err != nil {
-			return nil, fmt.Errorf("invalid 'url' spec for CapsysKronos target: %w", err)
+			return nil, fmt.Errorf("invalid 'timezone' spec for CapsysKronos target: %w", err)
 		}
-		url = *url_
+		location = location_
+	}
+
+	var tags = CapsysKronosTags{}
+	for tag, fields := range spec.Tags {
+		tags[tag] = fields.values()
+	}
+
+	return &CapsysKronos{
+		Token:  spec.Token,
+		Url:    *url_,
+		Client: client,
+
+		Location: location,
+
+		Tags: tags,
+
+		Defaults: spec.Defaults,
+
+		issues: issueCache[bool]{prefix: "CapsysKronos/" + url_.String() + "/", cache: cache_},
+	}, nil
+}
+
+type JiraWorklogDefaults struct {
+	Comment string `yaml:"comment"`
+}
+type JiraWorklog struct {
+	// Email selects basic authentication (Jira Cloud), otherwise the token is used as a personal access token (Jira Data Center)
+	Email  *string
+	Token  string
+	Url    url.URL
+	Client *httpclient.Client
+
+	Defaults JiraWorklogDefaults
+
+	issues issueCache[bool]
+}
+
+// Jira expects timestamps with milliseconds and a numeric zone offset without a colon
+const jiraWorklogTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+type jiraWorklogUser struct {
+	AccountId string `json:"accountId"`
+	Key       string `json:"key"`
+	Name      string `json:"name"`
+}
+type jiraWorklogEntry struct {
+	Id               string           `json:"id,omitempty"`
+	Author           *jiraWorklogUser `json:"author,omitempty"`
+	Started          string           `json:"started"`
+	TimeSpentSeconds int              `json:"timeSpentSeconds"`
+	Comment          string           `json:"comment"`
+}
+
+// jiraWorklogPage is the position of a page within the paged results of Jira.
+type jiraWorklogPage struct {
+	StartAt int `json:"startAt"`
+	Total   int `json:"total"`
+}
+type jiraWorklogEntries struct {
+	jiraWorklogPage
+	Worklogs []jiraWorklogEntry `json:"worklogs"`
+}
+type jiraWorklogIssue struct {
+	Key string `json:"key"`
+}
+type jiraWorklogSearch struct {
+	jiraWorklogPage
+	Issues []jiraWorklogIssue `json:"issues"`
+}
+
+func (j *JiraWorklog) do(ctx context.Context, method string, path string, body any, out any) error {
+	reference, err := url.Parse(path)
+	if err != nil {
+		return err
 	}
 
-	var timeout = 10 * time.Second
-	if timeoutParam, ok := spec["timeout"].(string); ok {
-		timeoutDuration, err := time.ParseDuration(timeoutParam)
+	var requestBody io.Reader = nil
+	if body != nil {
+		content, err := json.Marshal(body)
 		if err != nil {
-			return nil, fmt.Errorf("invalid 'timeout' spec for CapsysKronos target: %w", err)
+			return err
 		}
-		timeout = timeoutDuration
+		requestBody = bytes.NewBuffer(content)
 	}
 
-	var ca *string = nil
-	if caParam, ok := spec["ca"].(string); ok {
-		ca = &caParam
+	request, err := http.NewRequestWithContext(ctx, method, j.Url.ResolveReference(reference).String(), requestBody)
+	if err != nil {
+		return err
 	}
 
-	var tags = CapsysKronosTags{}
-	if tagsParam, ok := spec["tags"].(map[string]any); ok {
-		for k, v := range tagsParam {
-			if tagsParamInner, ok := v.(map[string]any); ok {
-				tags[k] = tagsParamInner
+	if j.Email != nil {
+		request.SetBasicAuth(*j.Email, j.Token)
+	} else {
+		request.Header.Set("Authorization", "Bearer "+j.Token)
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := j.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return &jiraWorklogError{Method: method, Path: reference.Path, StatusCode: response.StatusCode, Body: strings.ReplaceAll(string(responseBody), "\n", "")}
+	}
+
+	if out == nil || len(responseBody) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(responseBody, out)
+}
+
+type jiraWorklogError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Body       string
+}
+
+func (e *jiraWorklogError) Error() string {
+	return fmt.Sprintf("JiraWorklog %s %s failed (%d): %s", e.Method, e.Path, e.StatusCode, e.Body)
+}
+
+// Worklog identifiers are only addressable through their issue, so both are kept in the identifier.
+func (j *JiraWorklog) splitWorklog(worklog string) (string, string, error) {
+	issue, id, ok := strings.Cut(worklog, "/")
+	if !ok {
+		return "", "", fmt.Errorf("invalid JiraWorklog worklog identifier: %s", worklog)
+	}
+	return issue, id, nil
+}
+
+func (j *JiraWorklog) convertEntry(entry TimeEntry) jiraWorklogEntry {
+	return jiraWorklogEntry{
+		Started:          entry.From.Truncate(time.Minute).Format(jiraWorklogTimeLayout),
+		TimeSpentSeconds: int(entry.Till.Sub(entry.From).Truncate(time.Minute).Seconds()),
+		Comment:          utils.DefaultString(entry.Description, j.Defaults.Comment),
+	}
+}
+
+func (j *JiraWorklog) convertWorklog(issue string, worklog jiraWorklogEntry) (TimeEntry, error) {
+	from, err := time.Parse(jiraWorklogTimeLayout, worklog.Started)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	return TimeEntry{
+		Id:          issue + "/" + worklog.Id,
+		Issue:       issue,
+		From:        from,
+		Till:        from.Add(time.Duration(worklog.TimeSpentSeconds) * time.Second),
+		Description: worklog.Comment,
+	}, nil
+}
+
+func (j *JiraWorklog) getIssue(ctx context.Context, issue string) (bool, error) {
+	if err := j.do(ctx, "GET", fmt.Sprintf("/rest/api/2/issue/%s", url.PathEscape(issue)), nil, nil); err != nil {
+		var jiraErr *jiraWorklogError
+		if errors.As(err, &jiraErr) && jiraErr.StatusCode == http.StatusNotFound {
+			return false, issueNotFound{fmt.Errorf("invalid JiraWorklog issue (%d)", jiraErr.StatusCode)}
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (j *JiraWorklog) Verify(ctx context.Context) error {
+	var myself jiraWorklogUser
+	return j.do(ctx, "GET", "/rest/api/2/myself", nil, &myself)
+}
+
+func (j *JiraWorklog) validateTimeEntryIssue(ctx context.Context, entry TimeEntry) error {
+	_, err := j.issues.resolve(ctx, entry.Issue, j.getIssue)
+	return err
+}
+
+func (j *JiraWorklog) PushTimeEntry(ctx context.Context, entry TimeEntry) (string, error) {
+	if err := j.validateTimeEntryIssue(ctx, entry); err != nil {
+		return "", err
+	}
+
+	var created jiraWorklogEntry
+	if err := j.do(ctx, "POST", fmt.Sprintf("/rest/api/2/issue/%s/worklog", url.PathEscape(entry.Issue)), j.convertEntry(entry), &created); err != nil {
+		return "", err
+	}
+
+	return entry.Issue + "/" + created.Id, nil
+}
+
+func (j *JiraWorklog) UpdateTimeEntry(ctx context.Context, worklog string, entry TimeEntry) (string, error) {
+	issue, id, err := j.splitWorklog(worklog)
+	if err != nil {
+		return "", err
+	}
+
+	// Jira cannot move worklogs between issues, so those are recreated instead. The old worklog is deleted
+	// first: if creating the new one fails, the next run deletes the old one again (which is a no-op) and
+	// retries, instead of booking the time twice.
+	if issue != entry.Issue {
+		if err := j.validateTimeEntryIssue(ctx, entry); err != nil {
+			return "", err
+		}
+
+		if err := j.DeleteTimeEntry(ctx, worklog); err != nil {
+			return "", err
+		}
+
+		return j.PushTimeEntry(ctx, entry)
+	}
+
+	return worklog, j.do(ctx, "PUT", fmt.Sprintf("/rest/api/2/issue/%s/worklog/%s", url.PathEscape(issue), url.PathEscape(id)), j.convertEntry(entry), nil)
+}
+
+func (j *JiraWorklog) DeleteTimeEntry(ctx context.Context, worklog string) error {
+	issue, id, err := j.splitWorklog(worklog)
+	if err != nil {
+		return err
+	}
+
+	err = j.do(ctx, "DELETE", fmt.Sprintf("/rest/api/2/issue/%s/worklog/%s", url.PathEscape(issue), url.PathEscape(id)), nil, nil)
+
+	// An already deleted worklog is the desired end state
+	var jiraErr *jiraWorklogError
+	if errors.As(err, &jiraErr) && jiraErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	return err
+}
+
+func (j *JiraWorklog) ListTimeEntries(ctx context.Context, from time.Time, till time.Time) ([]TimeEntry, error) {
+	var myself jiraWorklogUser
+	if err := j.do(ctx, "GET", "/rest/api/2/myself", nil, &myself); err != nil {
+		return nil, err
+	}
+
+	// worklogDate is day based, the exact window is applied below
+	jql := fmt.Sprintf(`worklogAuthor = currentUser() AND worklogDate >= "%s" AND worklogDate <= "%s"`, from.Format(time.DateOnly), till.Format(time.DateOnly))
+
+	// Jira caps the size of pages (e.g. 100 issues on Jira Cloud) regardless of the requested size
+	var issues []jiraWorklogIssue
+	for {
+		var search jiraWorklogSearch
+		if err := j.do(ctx, "GET", fmt.Sprintf("/rest/api/2/search?jql=%s&fields=key&startAt=%d&maxResults=100", url.QueryEscape(jql), len(issues)), nil, &search); err != nil {
+			return nil, err
+		}
+
+		issues = append(issues, search.Issues...)
+		if len(search.Issues) == 0 || len(issues) >= search.Total {
+			break
+		}
+	}
+
+	var entries []TimeEntry
+	for _, issue := range issues {
+		var worklogs []jiraWorklogEntry
+		for {
+			var page jiraWorklogEntries
+			if err := j.do(ctx, "GET", fmt.Sprintf("/rest/api/2/issue/%s/worklog?startAt=%d", url.PathEscape(issue.Key), len(worklogs)), nil, &page); err != nil {
+				return nil, err
+			}
+
+			worklogs = append(worklogs, page.Worklogs...)
+			if len(page.Worklogs) == 0 || len(worklogs) >= page.Total {
+				break
+			}
+		}
+
+		for _, worklog := range worklogs {
+			if worklog.Author == nil || !j.isAuthor(myself, *worklog.Author) {
+				continue
+			}
+
+			entry, err := j.convertWorklog(issue.Key, worklog)
+			if err != nil {
+				return nil, err
+			}
+
+			if entry.StartsWithin(from, till) {
+				entries = append(entries, entry)
 			}
 		}
 	}
 
-	var defaults = map[string]any{}
-	if defaultsParam, ok := spec["defaults"].(map[string]any); ok {
-		defaults = defaultsParam
+	slices.SortFunc(entries, func(a TimeEntry, b TimeEntry) int { return a.From.Compare(b.From) })
+
+	return entries, nil
+}
+
+// Jira Cloud identifies users by account id, Jira Data Center by key and name.
+func (j *JiraWorklog) isAuthor(myself jiraWorklogUser, author jiraWorklogUser) bool {
+	if myself.AccountId != "" {
+		return myself.AccountId == author.AccountId
 	}
+	return myself.Key == author.Key && myself.Name == author.Name
+}
+
+func (j *JiraWorklog) MapTimeEntry(entry TimeEntry) TimeEntry {
+	mapped, _ := j.convertWorklog(entry.Issue, j.convertEntry(entry))
+	mapped.Id = entry.Id
+
+	return mapped
+}
+
+func (j *JiraWorklog) PreviewTimeEntry(ctx context.Context, entry TimeEntry) (any, error) {
+	return j.convertEntry(entry), nil
+}
+
+type JiraWorklogSpec struct {
+	// Email selects basic authentication (Jira Cloud), otherwise the token is used as a personal access token (Jira Data Center)
+	Email string `yaml:"email,omitempty"`
+	Token string `yaml:"token" schema:"required"`
+	Url   string `yaml:"url" schema:"required"`
+
+	httpclient.Spec `yaml:",inline"`
 
-	var activityCategoryId = 3
-	if activityCategoryIdParam, ok := defaults["activityCategoryId"].(int); ok {
-		activityCategoryId = activityCategoryIdParam
+	Defaults JiraWorklogDefaults `yaml:"defaults"`
+}
+
+func defaultJiraWorklogSpec() JiraWorklogSpec {
+	return JiraWorklogSpec{
+		Spec: httpclient.DefaultSpec(),
 	}
+}
 
-	var activityTypeId = 5
-	if activityTypeIdParam, ok := defaults["activityTypeId"].(int); ok {
-		activityTypeId = activityTypeIdParam
+func createJiraWorklog(spec JiraWorklogSpec, cache_ *cache.Cache) (*JiraWorklog, error) {
+	var email *string = nil
+	if spec.Email != "" {
+		email = &spec.Email
 	}
 
-	var siteId = 31
-	if siteIdParam, ok := defaults["siteId"].(int); ok {
-		siteId = siteIdParam
+	if spec.Token == "" {
+		return nil, fmt.Errorf("invalid or missing 'token' spec for JiraWorklog target")
 	}
 
-	var comment = ""
-	if commentParam, ok := defaults["comment"].(string); ok {
-		comment = commentParam
+	if spec.Url == "" {
+		return nil, fmt.Errorf("invalid or missing 'url' spec for JiraWorklog target")
 	}
 
-	return &CapsysKronos{
-		Token:   token,
-		Url:     url,
-		Timeout: timeout,
-		Ca:      ca,
+	url_, err := url.Parse(spec.Url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'url' spec for JiraWorklog target: %w", err)
+	}
 
-		Tags: tags,
+	client, err := httpclient.NewFromSpec(spec.Spec, "JiraWorklog target")
+	if err != nil {
+		return nil, err
+	}
 
-		Defaults: CapsysKronosDefaults{
-			ActivityCategoryId: activityCategoryId,
-			ActivityTypeId:     activityTypeId,
-			SiteId:             siteId,
-			Comment:            comment,
-		},
+	return &JiraWorklog{
+		Email:  email,
+		Token:  spec.Token,
+		Url:    *url_,
+		Client: client,
+
+		Defaults: spec.Defaults,
+
+		issues: issueCache[bool]{prefix: "JiraWorklog/" + url_.String() + "/", cache: cache_},
+	}, nil
+}
+
+type TempoTags map[string]map[string]any
+type TempoDefaults struct {
+	Description string         `yaml:"description"`
+	Attributes  map[string]any `yaml:"attributes,omitempty"`
+}
+type Tempo struct {
+	Account string
+
+	Token  string
+	Url    url.URL
+	Client *httpclient.Client
+
+	// Tempo addresses issues by their numeric id, which is resolved through Jira
+	Jira *JiraWorklog
+
+	Location *time.Location
+
+	Tags TempoTags
+
+	Defaults TempoDefaults
+
+	// Issues are cached by both their key and their id
+	issues issueCache[tempoJiraIssue]
+}
+
+type tempoWorklogAttribute struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+}
+type tempoWorklogInput struct {
+	AuthorAccountId  string                  `json:"authorAccountId"`
+	IssueId          int                     `json:"issueId"`
+	StartDate        string                  `json:"startDate"`
+	StartTime        string                  `json:"startTime"`
+	TimeSpentSeconds int                     `json:"timeSpentSeconds"`
+	Description      string                  `json:"description"`
+	Attributes       []tempoWorklogAttribute `json:"attributes"`
+}
+type tempoWorklogIssue struct {
+	Id int `json:"id"`
+}
+type tempoWorklogAttributes struct {
+	Values []tempoWorklogAttribute `json:"values"`
+}
+type tempoWorklog struct {
+	TempoWorklogId   int                    `json:"tempoWorklogId"`
+	Issue            tempoWorklogIssue      `json:"issue"`
+	StartDate        string                 `json:"startDate"`
+	StartTime        string                 `json:"startTime"`
+	TimeSpentSeconds int                    `json:"timeSpentSeconds"`
+	Description      string                 `json:"description"`
+	Attributes       tempoWorklogAttributes `json:"attributes"`
+}
+type tempoWorklogsMetadata struct {
+	Next string `json:"next"`
+}
+type tempoWorklogs struct {
+	Results  []tempoWorklog        `json:"results"`
+	Metadata tempoWorklogsMetadata `json:"metadata"`
+}
+type tempoJiraIssue struct {
+	Id  string `json:"id"`
+	Key string `json:"key"`
+}
+
+func (t *Tempo) do(ctx context.Context, method string, path string, body any, out any) error {
+	reference, err := url.Parse(path)
+	if err != nil {
+		return err
+	}
+
+	var requestBody io.Reader = nil
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		requestBody = bytes.NewBuffer(content)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, t.Url.ResolveReference(reference).String(), requestBody)
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Authorization", "Bearer "+t.Token)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := t.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	// An already deleted worklog is the desired end state
+	if method == "DELETE" && response.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("Tempo %s %s failed (%d): %s", method, reference.Path, response.StatusCode, strings.ReplaceAll(string(responseBody), "\n", ""))
+	}
+
+	if out == nil || len(responseBody) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(responseBody, out)
+}
+
+func (t *Tempo) getIssue(ctx context.Context, issue string) (tempoJiraIssue, error) {
+	var resolved tempoJiraIssue
+	if err := t.Jira.do(ctx, "GET", fmt.Sprintf("/rest/api/2/issue/%s?fields=key", url.PathEscape(issue)), nil, &resolved); err != nil {
+		var jiraErr *jiraWorklogError
+		if errors.As(err, &jiraErr) && jiraErr.StatusCode == http.StatusNotFound {
+			return tempoJiraIssue{}, issueNotFound{fmt.Errorf("invalid Tempo issue %s (%d)", issue, jiraErr.StatusCode)}
+		}
+		return tempoJiraIssue{}, err
+	}
+
+	// Pushed entries refer to the issue by its key, listed worklogs by its id
+	if issue == resolved.Key {
+		t.issues.put(resolved.Id, resolved)
+	} else {
+		t.issues.put(resolved.Key, resolved)
+	}
+
+	return resolved, nil
+}
+
+func (t *Tempo) resolveIssue(ctx context.Context, issue string) (tempoJiraIssue, error) {
+	return t.issues.resolve(ctx, issue, t.getIssue)
+}
+
+func (t *Tempo) Verify(ctx context.Context) error {
+	if err := t.Jira.Verify(ctx); err != nil {
+		return err
+	}
+
+	return t.do(ctx, "GET", "/4/work-attributes", nil, nil)
+}
+
+func (t *Tempo) convertAttributes(entry TimeEntry) map[string]any {
+	attributes := maps.Clone(t.Defaults.Attributes)
+	if attributes == nil {
+		attributes = map[string]any{}
+	}
+
+	for _, tag := range entry.Tags {
+		if tagData, ok := t.Tags[tag]; ok {
+			maps.Copy(attributes, tagData)
+		}
+	}
+	maps.Copy(attributes, entry.Fields)
+
+	return attributes
+}
+
+func (t *Tempo) convertEntry(ctx context.Context, entry TimeEntry) (tempoWorklogInput, error) {
+	issue, err := t.resolveIssue(ctx, entry.Issue)
+	if err != nil {
+		return tempoWorklogInput{}, err
+	}
+
+	issueId, err := strconv.Atoi(issue.Id)
+	if err != nil {
+		return tempoWorklogInput{}, fmt.Errorf("invalid Jira issue id %s: %w", issue.Id, err)
+	}
+
+	// Tempo is unaware of timezones, start dates and times are local to the configured location
+	from := entry.From.Truncate(time.Minute).In(t.Location)
+
+	values := t.convertAttributes(entry)
+
+	var attributes []tempoWorklogAttribute
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		attributes = append(attributes, tempoWorklogAttribute{Key: key, Value: values[key]})
+	}
+
+	return tempoWorklogInput{
+		AuthorAccountId:  t.Account,
+		IssueId:          issueId,
+		StartDate:        from.Format(time.DateOnly),
+		StartTime:        from.Format(time.TimeOnly),
+		TimeSpentSeconds: int(entry.Till.Sub(entry.From).Truncate(time.Minute).Seconds()),
+		Description:      utils.DefaultString(entry.Description, t.Defaults.Description),
+		Attributes:       attributes,
+	}, nil
+}
+
+func (t *Tempo) convertWorklog(ctx context.Context, worklog tempoWorklog) (TimeEntry, error) {
+	issue, err := t.resolveIssue(ctx, strconv.Itoa(worklog.Issue.Id))
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	from, err := time.ParseInLocation(time.DateTime, worklog.StartDate+" "+worklog.StartTime, t.Location)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	fields := map[string]any{}
+	for _, attribute := range worklog.Attributes.Values {
+		fields[attribute.Key] = attribute.Value
+	}
+
+	return TimeEntry{
+		Id:          strconv.Itoa(worklog.TempoWorklogId),
+		Issue:       issue.Key,
+		From:        from,
+		Till:        from.Add(time.Duration(worklog.TimeSpentSeconds) * time.Second),
+		Description: worklog.Description,
+		Fields:      fields,
+	}, nil
+}
+
+func (t *Tempo) PushTimeEntry(ctx context.Context, entry TimeEntry) (string, error) {
+	input, err := t.convertEntry(ctx, entry)
+	if err != nil {
+		return "", err
+	}
+
+	var created tempoWorklog
+	if err := t.do(ctx, "POST", "/4/worklogs", input, &created); err != nil {
+		return "", err
+	}
+
+	return strconv.Itoa(created.TempoWorklogId), nil
+}
+
+func (t *Tempo) UpdateTimeEntry(ctx context.Context, worklog string, entry TimeEntry) (string, error) {
+	input, err := t.convertEntry(ctx, entry)
+	if err != nil {
+		return "", err
+	}
+
+	return worklog, t.do(ctx, "PUT", fmt.Sprintf("/4/worklogs/%s", url.PathEscape(worklog)), input, nil)
+}
+
+func (t *Tempo) DeleteTimeEntry(ctx context.Context, worklog string) error {
+	return t.do(ctx, "DELETE", fmt.Sprintf("/4/worklogs/%s", url.PathEscape(worklog)), nil, nil)
+}
+
+func (t *Tempo) ListTimeEntries(ctx context.Context, from time.Time, till time.Time) ([]TimeEntry, error) {
+	// Tempo filters by whole (inclusive) days, the exact window is applied below
+	next := fmt.Sprintf("/4/worklogs/user/%s?from=%s&to=%s&limit=1000", url.PathEscape(t.Account), from.In(t.Location).Format(time.DateOnly), till.In(t.Location).Format(time.DateOnly))
+
+	var entries []TimeEntry
+	for next != "" {
+		var worklogs tempoWorklogs
+		if err := t.do(ctx, "GET", next, nil, &worklogs); err != nil {
+			return nil, err
+		}
+
+		for _, worklog := range worklogs.Results {
+			entry, err := t.convertWorklog(ctx, worklog)
+			if err != nil {
+				return nil, err
+			}
+
+			if entry.StartsWithin(from, till) {
+				entries = append(entries, entry)
+			}
+		}
+
+		next = worklogs.Metadata.Next
+	}
+
+	return entries, nil
+}
+
+func (t *Tempo) MapTimeEntry(entry TimeEntry) TimeEntry {
+	from := entry.From.Truncate(time.Minute).In(t.Location)
+
+	return TimeEntry{
+		Id:          entry.Id,
+		Issue:       entry.Issue,
+		From:        from,
+		Till:        from.Add(entry.Till.Sub(entry.From).Truncate(time.Minute)),
+		Description: utils.DefaultString(entry.Description, t.Defaults.Description),
+		Fields:      t.convertAttributes(entry),
+	}
+}
+
+func (t *Tempo) PreviewTimeEntry(ctx context.Context, entry TimeEntry) (any, error) {
+	return t.convertEntry(ctx, entry)
+}
+
+type TempoSpec struct {
+	Account string `yaml:"account" schema:"required"`
+	Token   string `yaml:"token" schema:"required"`
+	Url     string `yaml:"url"`
+
+	httpclient.Spec `yaml:",inline"`
+
+	// Jira resolves issue keys into the numeric ids Tempo addresses issues by
+	Jira JiraWorklogSpec `yaml:"jira" schema:"required"`
+
+	// Timezone defaults to the global timezone
+	Timezone string `yaml:"timezone,omitempty"`
+
+	Tags TempoTags `yaml:"tags,omitempty"`
+
+	Defaults TempoDefaults `yaml:"defaults"`
+}
+
+func defaultTempoSpec() TempoSpec {
+	return TempoSpec{
+		Url:  "https://api.tempo.io",
+		Spec: httpclient.DefaultSpec(),
+		Jira: defaultJiraWorklogSpec(),
+	}
+}
+
+func createTempo(spec TempoSpec, location *time.Location, cache_ *cache.Cache) (*Tempo, error) {
+	if spec.Account == "" {
+		return nil, fmt.Errorf("invalid or missing 'account' spec for Tempo target")
+	}
+
+	if spec.Token == "" {
+		return nil, fmt.Errorf("invalid or missing 'token' spec for Tempo target")
+	}
+
+	url_, err := url.Parse(spec.Url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'url' spec for Tempo target: %w", err)
+	}
+
+	client, err := httpclient.NewFromSpec(spec.Spec, "Tempo target")
+	if err != nil {
+		return nil, err
+	}
+
+	jira, err := createJiraWorklog(spec.Jira, cache_)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'jira' spec for Tempo target: %w", err)
+	}
+
+	if spec.Timezone != "" {
+		location_, err := time.LoadLocation(spec.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'timezone' spec for Tempo target: %w", err)
+		}
+		location = location_
+	}
+
+	return &Tempo{
+		Account: spec.Account,
+
+		Token:  spec.Token,
+		Url:    *url_,
+		Client: client,
+
+		Jira: jira,
+
+		Location: location,
+
+		Tags: spec.Tags,
+
+		Defaults: spec.Defaults,
+
+		issues: issueCache[tempoJiraIssue]{prefix: "Tempo/" + url_.String() + "/", cache: cache_},
 	}, nil
 }
 
 type TimeEntryTargetConfig struct {
-	Kind string         `yaml:"kind"`
-	Spec map[string]any `yaml:"spec"`
+	Name string `yaml:"name,omitempty"`
+	Kind string `yaml:"kind" schema:"required"`
+	// Spec is decoded once the kind is known, into the spec of the kind (e.g. CapsysKronosSpec)
+	Spec   yaml.Node       `yaml:"spec,omitempty"`
+	Filter TimeEntryFilter `yaml:"filter,omitempty"`
+}
+
+// NamedTimeEntryTarget is a configured target together with the filter selecting the entries it receives.
+type NamedTimeEntryTarget struct {
+	TimeEntryTarget
+
+	Name   string
+	Filter TimeEntryFilter
+}
+
+// NewTimeEntryTarget creates the target of the config, location is the default timezone of targets unaware of
+// timezones and the cache (if any) remembers issue lookups across runs.
+func NewTimeEntryTarget(config TimeEntryTargetConfig, location *time.Location, cache_ *cache.Cache) (TimeEntryTarget, error) {
+	return create(TimeEntryTargetKinds, "target", config.Kind, &config.Spec, environment{location: location, cache: cache_})
+}
+
+// ResolveTimeEntryTargetConfig returns the config with the defaults of the target applied to its spec.
+func ResolveTimeEntryTargetConfig(config TimeEntryTargetConfig, location *time.Location) (TimeEntryTargetConfig, error) {
+	spec, err := resolve(TimeEntryTargetKinds, "target", config.Kind, &config.Spec, environment{location: location})
+	if err != nil {
+		return config, err
+	}
+
+	config.Spec = spec
+
+	return config, nil
 }
 
-func NewTimeEntryTarget(config TimeEntryTargetConfig) (TimeEntryTarget, error) {
-	switch config.Kind {
-	case "CapsysKronos":
-		return createCapsysKronos(config.Spec)
-	default:
-		return nil, fmt.Errorf("unsupported time entry target: %s", config.Kind)
+func NewTimeEntryTargets(configs []TimeEntryTargetConfig, location *time.Location, cache_ *cache.Cache) ([]NamedTimeEntryTarget, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no time entry target configured")
 	}
+
+	var targets []NamedTimeEntryTarget
+	for _, config := range configs {
+		target, err := NewTimeEntryTarget(config, location, cache_)
+		if err != nil {
+			return nil, err
+		}
+
+		name := utils.DefaultString(config.Name, config.Kind)
+		if slices.ContainsFunc(targets, func(target NamedTimeEntryTarget) bool { return target.Name == name }) {
+			return nil, fmt.Errorf("duplicate time entry target name: %s", name)
+		}
+
+		targets = append(targets, NamedTimeEntryTarget{TimeEntryTarget: target, Name: name, Filter: config.Filter})
+	}
+
+	return targets, nil
 }
@@ -0,0 +1,286 @@
+package entries
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tornermarton/timesheets/internal/arrays"
+)
+
+func TestJiraWorklogPushTimeEntry(t *testing.T) {
+	var posted jiraWorklogEntry
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if email, token, ok := r.BasicAuth(); !ok || email != "me@example.com" || token != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/rest/api/2/issue/ABC-1":
+			w.Write([]byte(`{"key": "ABC-1"}`))
+		case r.Method == "POST" && r.URL.Path == "/rest/api/2/issue/ABC-1/worklog":
+			json.NewDecoder(r.Body).Decode(&posted)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": "10001"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	url_, _ := url.Parse(server.URL)
+	email := "me@example.com"
+	jira := &JiraWorklog{Email: &email, Token: "secret", Url: *url_, Client: newTestClient(), Defaults: JiraWorklogDefaults{Comment: "Development"}}
+
+	from := time.Date(2025, 6, 1, 9, 0, 30, 0, time.FixedZone("CEST", 2*60*60))
+	worklog, err := jira.PushTimeEntry(context.Background(), TimeEntry{Issue: "ABC-1", From: from, Till: from.Add(90*time.Minute + 20*time.Second)})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if worklog != "ABC-1/10001" {
+		t.Errorf("unexpected worklog identifier: %s", worklog)
+	}
+	if posted.Started != "2025-06-01T09:00:00.000+0200" || posted.TimeSpentSeconds != 90*60 || posted.Comment != "Development" {
+		t.Errorf("unexpected worklog: %+v", posted)
+	}
+
+	if _, err := jira.PushTimeEntry(context.Background(), TimeEntry{Issue: "XYZ-9", From: from, Till: from.Add(time.Hour)}); err == nil {
+		t.Errorf("expected unknown issue to be rejected")
+	}
+}
+
+func TestJiraWorklogUpdateTimeEntry(t *testing.T) {
+	var requests []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch {
+		case r.Method == "GET" && (r.URL.Path == "/rest/api/2/issue/ABC-1" || r.URL.Path == "/rest/api/2/issue/ABC-2"):
+			w.Write([]byte(`{"key": "ABC-2"}`))
+		case r.Method == "PUT" && r.URL.Path == "/rest/api/2/issue/ABC-1/worklog/10001":
+			w.Write([]byte(`{"id": "10001"}`))
+		case r.Method == "DELETE" && r.URL.Path == "/rest/api/2/issue/ABC-1/worklog/10001":
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == "POST" && r.URL.Path == "/rest/api/2/issue/ABC-2/worklog":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": "10002"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	url_, _ := url.Parse(server.URL)
+	jira := &JiraWorklog{Token: "secret", Url: *url_, Client: newTestClient()}
+
+	from := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	worklog, err := jira.UpdateTimeEntry(context.Background(), "ABC-1/10001", TimeEntry{Issue: "ABC-1", From: from, Till: from.Add(time.Hour)})
+	if err != nil || worklog != "ABC-1/10001" {
+		t.Errorf("expected the worklog to be updated in place, got %s (%v)", worklog, err)
+	}
+
+	// Worklogs cannot be moved between issues, so they are recreated on the new issue
+	requests = nil
+	worklog, err = jira.UpdateTimeEntry(context.Background(), "ABC-1/10001", TimeEntry{Issue: "ABC-2", From: from, Till: from.Add(time.Hour)})
+	if err != nil || worklog != "ABC-2/10002" {
+		t.Errorf("expected the worklog to be recreated, got %s (%v)", worklog, err)
+	}
+	if !slices.Equal(requests, []string{"GET /rest/api/2/issue/ABC-2", "DELETE /rest/api/2/issue/ABC-1/worklog/10001", "POST /rest/api/2/issue/ABC-2/worklog"}) {
+		t.Errorf("unexpected requests: %v", requests)
+	}
+}
+
+func TestJiraWorklogListTimeEntriesPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startAt := r.URL.Query().Get("startAt")
+
+		// Pages hold a single result, whatever size is requested
+		switch r.URL.Path {
+		case "/rest/api/2/myself":
+			w.Write([]byte(`{"accountId": "me"}`))
+		case "/rest/api/2/search":
+			w.Write([]byte(fmt.Sprintf(`{"startAt": %s, "total": 2, "issues": [{"key": "ABC-%s"}]}`, startAt, startAt)))
+		case "/rest/api/2/issue/ABC-0/worklog", "/rest/api/2/issue/ABC-1/worklog":
+			w.Write([]byte(fmt.Sprintf(`{"startAt": %s, "total": 2, "worklogs": [{"id": "%s", "author": {"accountId": "me"}, "started": "2025-06-01T09:00:00.000+0000", "timeSpentSeconds": 3600}]}`, startAt, startAt)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	url_, _ := url.Parse(server.URL)
+	jira := &JiraWorklog{Token: "secret", Url: *url_, Client: newTestClient()}
+
+	entries, err := jira.ListTimeEntries(context.Background(), time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ids := arrays.Map(entries, func(entry TimeEntry) string { return entry.Id })
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"ABC-0/0", "ABC-0/1", "ABC-1/0", "ABC-1/1"}) {
+		t.Errorf("expected the worklogs of every page, got %v", ids)
+	}
+}
+
+func TestTempoPushTimeEntry(t *testing.T) {
+	var posted tempoWorklogInput
+
+	jiraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/rest/api/2/issue/ABC-1" {
+			w.Write([]byte(`{"id": "10042", "key": "ABC-1"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer jiraServer.Close()
+
+	tempoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.Method == "POST" && r.URL.Path == "/4/worklogs" {
+			json.NewDecoder(r.Body).Decode(&posted)
+			w.Write([]byte(`{"tempoWorklogId": 7}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer tempoServer.Close()
+
+	jiraUrl, _ := url.Parse(jiraServer.URL)
+	tempoUrl, _ := url.Parse(tempoServer.URL)
+	location, _ := time.LoadLocation("Europe/Budapest")
+
+	tempo := &Tempo{
+		Account:  "acc-1",
+		Token:    "secret",
+		Url:      *tempoUrl,
+		Client:   newTestClient(),
+		Jira:     &JiraWorklog{Token: "pat", Url: *jiraUrl, Client: newTestClient()},
+		Location: location,
+		Tags: TempoTags{
+			"meeting": {"_Activity_": "Meeting"},
+		},
+		Defaults: TempoDefaults{
+			Attributes: map[string]any{"_Activity_": "Development", "_Account_": "INTERNAL"},
+		},
+	}
+
+	from := time.Date(2025, 6, 1, 7, 0, 0, 0, time.UTC)
+	worklog, err := tempo.PushTimeEntry(context.Background(), TimeEntry{Issue: "ABC-1", From: from, Till: from.Add(30 * time.Minute), Description: "Standup", Tags: []string{"meeting"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if worklog != "7" {
+		t.Errorf("unexpected worklog identifier: %s", worklog)
+	}
+	if posted.IssueId != 10042 || posted.AuthorAccountId != "acc-1" || posted.StartDate != "2025-06-01" || posted.StartTime != "09:00:00" || posted.TimeSpentSeconds != 1800 {
+		t.Errorf("unexpected worklog: %+v", posted)
+	}
+
+	expected := []tempoWorklogAttribute{{Key: "_Account_", Value: "INTERNAL"}, {Key: "_Activity_", Value: "Meeting"}}
+	if len(posted.Attributes) != len(expected) || posted.Attributes[0] != expected[0] || posted.Attributes[1] != expected[1] {
+		t.Errorf("unexpected attributes: %+v", posted.Attributes)
+	}
+}
+
+func TestCapsysKronosConvertEntryTimezones(t *testing.T) {
+	budapest, _ := time.LoadLocation("Europe/Budapest")
+	newYork, _ := time.LoadLocation("America/New_York")
+
+	cases := []struct {
+		name     string
+		location *time.Location
+		from     time.Time
+		expected string
+	}{
+		{"budapest winter", budapest, time.Date(2025, 1, 15, 8, 0, 0, 0, time.UTC), "2025-01-15T09:00:00+01:00"},
+		{"budapest summer", budapest, time.Date(2025, 7, 15, 8, 0, 0, 0, time.UTC), "2025-07-15T10:00:00+02:00"},
+		{"budapest before spring forward", budapest, time.Date(2025, 3, 30, 0, 59, 0, 0, time.UTC), "2025-03-30T01:59:00+01:00"},
+		{"budapest after spring forward", budapest, time.Date(2025, 3, 30, 1, 0, 0, 0, time.UTC), "2025-03-30T03:00:00+02:00"},
+		{"budapest first fall back hour", budapest, time.Date(2025, 10, 26, 0, 30, 0, 0, time.UTC), "2025-10-26T02:30:00+02:00"},
+		{"budapest second fall back hour", budapest, time.Date(2025, 10, 26, 1, 30, 0, 0, time.UTC), "2025-10-26T02:30:00+01:00"},
+		{"new york before spring forward", newYork, time.Date(2025, 3, 9, 6, 59, 0, 0, time.UTC), "2025-03-09T01:59:00-05:00"},
+		{"new york after spring forward", newYork, time.Date(2025, 3, 9, 7, 0, 0, 0, time.UTC), "2025-03-09T03:00:00-04:00"},
+		{"new york first fall back hour", newYork, time.Date(2025, 11, 2, 5, 30, 0, 0, time.UTC), "2025-11-02T01:30:00-04:00"},
+		{"new york second fall back hour", newYork, time.Date(2025, 11, 2, 6, 30, 0, 0, time.UTC), "2025-11-02T01:30:00-05:00"},
+		{"utc", time.UTC, time.Date(2025, 3, 30, 1, 0, 45, 0, budapest), "2025-03-30T00:00:00Z"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kronos := &CapsysKronos{Location: c.location}
+
+			converted, err := kronos.convertEntry(TimeEntry{Issue: "ABC-1", From: c.from, Till: c.from.Add(time.Hour)})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if converted.WorklogInput.StartOffsetDateTime != c.expected {
+				t.Errorf("got %s, want %s", converted.WorklogInput.StartOffsetDateTime, c.expected)
+			}
+			if converted.WorklogInput.TimeSpent != 60 {
+				t.Errorf("got %v minutes, want 60", converted.WorklogInput.TimeSpent)
+			}
+		})
+	}
+}
+
+func TestCreateCapsysKronosTimezone(t *testing.T) {
+	spec := defaultCapsysKronosSpec()
+	spec.Token = "secret"
+
+	kronos, err := createCapsysKronos(spec, time.UTC, nil)
+	if err != nil || kronos.Location != time.UTC {
+		t.Errorf("expected the global timezone to be the default, got %v (%v)", kronos, err)
+	}
+
+	spec.Timezone = "Europe/Budapest"
+	kronos, err = createCapsysKronos(spec, time.UTC, nil)
+	if err != nil || kronos.Location.String() != "Europe/Budapest" {
+		t.Errorf("expected the spec timezone to be used, got %v (%v)", kronos, err)
+	}
+
+	spec.Timezone = "Mars/Olympus"
+	if _, err := createCapsysKronos(spec, time.UTC, nil); err == nil {
+		t.Errorf("expected an invalid timezone to be rejected")
+	}
+}
+
+func TestCapsysKronosVerify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/latest/myself" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"name": "me"}`))
+	}))
+	defer server.Close()
+
+	url_, _ := url.Parse(server.URL)
+	kronos := &CapsysKronos{Token: "secret", Url: *url_, Client: newTestClient()}
+
+	var verifier Verifier = kronos
+	if err := verifier.Verify(context.Background()); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	kronos.Token = "wrong"
+	if err := kronos.Verify(context.Background()); err == nil || err.Error() != "cannot verify CapsysKronos token (401)" {
+		t.Errorf("expected an invalid token to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,371 @@
+package entries
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tornermarton/timesheets/internal/constants"
+)
+
+type TimeEntryTransform interface {
+	TransformTimeEntries(entries []TimeEntry) ([]TimeEntry, error)
+}
+
+// lookback is implemented by the transforms whose output within a period depends on entries started before
+// it (e.g. the pieces of split entries), returning how much earlier entries are needed.
+type lookback interface {
+	Lookback() time.Duration
+}
+
+type Round struct {
+	Mode     string
+	Unit     time.Duration
+	Carry    bool
+	Location *time.Location
+}
+
+func (r *Round) round(duration time.Duration) time.Duration {
+	switch r.Mode {
+	case "up":
+		if rounded := duration.Truncate(r.Unit); rounded != duration {
+			return rounded + r.Unit
+		}
+		return duration
+	case "down":
+		return duration.Truncate(r.Unit)
+	default:
+		return duration.Round(r.Unit)
+	}
+}
+
+// TransformTimeEntries rounds the duration of every entry, keeping their start, entries rounded to nothing
+// are dropped. With carry enabled the rounding error is added to the next entry of the same (local) day, so
+// the total of the day stays within one unit of the tracked time. The carry starts over every day, so the
+// entries of a day are rounded the same regardless of the pulled period.
+func (r *Round) TransformTimeEntries(entries []TimeEntry) ([]TimeEntry, error) {
+	carries := map[string]time.Duration{}
+
+	out := make([]TimeEntry, 0, len(entries))
+	for _, entry := range entries {
+		day := entry.From.In(r.Location).Format(time.DateOnly)
+
+		duration := entry.Till.Sub(entry.From)
+		if r.Carry {
+			duration += carries[day]
+		}
+
+		rounded := max(r.round(duration), 0)
+		carries[day] = duration - rounded
+
+		if rounded == 0 {
+			continue
+		}
+
+		entry.Till = entry.From.Add(rounded)
+		out = append(out, entry)
+	}
+
+	return out, nil
+}
+
+type RoundSpec struct {
+	Mode    string `yaml:"mode" schema:"enum=up|down|nearest"`
+	Minutes int    `yaml:"minutes"`
+	Carry   bool   `yaml:"carry"`
+}
+
+func defaultRoundSpec() RoundSpec {
+	return RoundSpec{
+		Mode:    "nearest",
+		Minutes: 1,
+	}
+}
+
+func createRound(spec RoundSpec, location *time.Location) (*Round, error) {
+	if spec.Mode != "up" && spec.Mode != "down" && spec.Mode != "nearest" {
+		return nil, fmt.Errorf("invalid 'mode' spec for Round transform: %s (expected up, down or nearest)", spec.Mode)
+	}
+
+	if spec.Minutes < 1 {
+		return nil, fmt.Errorf("invalid 'minutes' spec for Round transform: %d", spec.Minutes)
+	}
+
+	return &Round{
+		Mode:     spec.Mode,
+		Unit:     time.Duration(spec.Minutes) * time.Minute,
+		Carry:    spec.Carry,
+		Location: location,
+	}, nil
+}
+
+type Drop struct {
+	Shorter time.Duration
+}
+
+// TransformTimeEntries drops the entries shorter than the threshold (e.g. timers started by accident).
+func (d *Drop) TransformTimeEntries(entries []TimeEntry) ([]TimeEntry, error) {
+	out := make([]TimeEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Till.Sub(entry.From) >= d.Shorter {
+			out = append(out, entry)
+		}
+	}
+
+	return out, nil
+}
+
+type DropSpec struct {
+	Shorter time.Duration `yaml:"shorter" schema:"required"`
+}
+
+func defaultDropSpec() DropSpec {
+	return DropSpec{}
+}
+
+func createDrop(spec DropSpec) (*Drop, error) {
+	if spec.Shorter <= 0 {
+		return nil, fmt.Errorf("invalid or missing 'shorter' spec for Drop transform")
+	}
+
+	return &Drop{
+		Shorter: spec.Shorter,
+	}, nil
+}
+
+type Merge struct {
+	Scope        string
+	Gap          time.Duration
+	Descriptions string
+	Location     *time.Location
+}
+
+func (m *Merge) key(entry TimeEntry) string {
+	tags := slices.Clone(entry.Tags)
+	slices.Sort(tags)
+
+	key := entry.Issue + "\x00" + strings.Join(tags, "\x00")
+	if m.Descriptions == "match" {
+		key += "\x00" + entry.Description
+	}
+	if m.Scope == "day" {
+		key += "\x00" + entry.From.In(m.Location).Format(time.DateOnly)
+	}
+	return key
+}
+
+// id identifies a merged entry by what its entries are merged by instead of by the entries themselves, so
+// adding, removing or editing an entry of the group updates the worklog of the group (instead of booking it
+// again under a new identity).
+func (m *Merge) id(group string) string {
+	hash := sha256.Sum256([]byte(group))
+	return "merge/" + hex.EncodeToString(hash[:8])
+}
+
+func (m *Merge) merge(into TimeEntry, entry TimeEntry) TimeEntry {
+	duration := into.Till.Sub(into.From) + entry.Till.Sub(entry.From)
+
+	switch {
+	case entry.Description == "" || m.Descriptions == "match":
+	case into.Description == "":
+		into.Description = entry.Description
+	case m.Descriptions == "concat" || !slices.Contains(strings.Split(into.Description, "; "), entry.Description):
+		into.Description += "; " + entry.Description
+	}
+
+	// Gaps between merged entries are not worked time, so the worklog lasts as long as the entries together
+	into.Till = into.From.Add(duration)
+
+	return into
+}
+
+// TransformTimeEntries merges the entries of the same issue and tags, either when they follow each other
+// within the gap tolerance or, with the day scope, whenever they start on the same (local) day.
+func (m *Merge) TransformTimeEntries(entries []TimeEntry) ([]TimeEntry, error) {
+	var out []TimeEntry
+
+	if m.Scope == "day" {
+		indexes := map[string]int{}
+		for _, entry := range entries {
+			key := m.key(entry)
+			if i, ok := indexes[key]; ok {
+				out[i] = m.merge(out[i], entry)
+				continue
+			}
+
+			indexes[key] = len(out)
+			entry.Id = m.id(key)
+			out = append(out, entry)
+		}
+
+		return out, nil
+	}
+
+	// Consecutive groups are told apart by their order among the groups of the same key on the same day
+	groups := map[string]int{}
+	for _, entry := range entries {
+		key := m.key(entry)
+		if n := len(out); n > 0 && m.key(out[n-1]) == key && entry.From.Sub(out[n-1].Till) <= m.Gap {
+			out[n-1] = m.merge(out[n-1], entry)
+			continue
+		}
+
+		group := key + "\x00" + entry.From.In(m.Location).Format(time.DateOnly)
+		groups[group]++
+		entry.Id = m.id(group + "\x00" + strconv.Itoa(groups[group]))
+		out = append(out, entry)
+	}
+
+	return out, nil
+}
+
+type MergeSpec struct {
+	Scope        string        `yaml:"scope" schema:"enum=consecutive|day"`
+	Gap          time.Duration `yaml:"gap"`
+	Descriptions string        `yaml:"descriptions" schema:"enum=match|concat|dedupe"`
+}
+
+func defaultMergeSpec() MergeSpec {
+	return MergeSpec{
+		Scope:        "consecutive",
+		Descriptions: "match",
+	}
+}
+
+func createMerge(spec MergeSpec, location *time.Location) (*Merge, error) {
+	if spec.Scope != "consecutive" && spec.Scope != "day" {
+		return nil, fmt.Errorf("invalid 'scope' spec for Merge transform: %s (expected consecutive or day)", spec.Scope)
+	}
+
+	if spec.Gap < 0 {
+		return nil, fmt.Errorf("invalid 'gap' spec for Merge transform: %s", spec.Gap)
+	}
+
+	if spec.Descriptions != "match" && spec.Descriptions != "concat" && spec.Descriptions != "dedupe" {
+		return nil, fmt.Errorf("invalid 'descriptions' spec for Merge transform: %s (expected match, concat or dedupe)", spec.Descriptions)
+	}
+
+	return &Merge{
+		Scope:        spec.Scope,
+		Gap:          spec.Gap,
+		Descriptions: spec.Descriptions,
+		Location:     location,
+	}, nil
+}
+
+type Split struct {
+	Location *time.Location
+}
+
+// TransformTimeEntries splits the entries spanning (local) midnight into one entry per day, the first
+// piece keeps the identity of the entry while the following ones are suffixed with their date.
+func (s *Split) TransformTimeEntries(entries []TimeEntry) ([]TimeEntry, error) {
+	out := make([]TimeEntry, 0, len(entries))
+	for _, entry := range entries {
+		piece := entry
+		for {
+			local := piece.From.In(s.Location)
+			midnight := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, s.Location)
+			if !midnight.Before(entry.Till) {
+				break
+			}
+
+			piece.Till = midnight
+			out = append(out, piece)
+
+			piece.Id = entry.Id + "@" + midnight.Format(time.DateOnly)
+			piece.From = midnight
+			piece.Till = entry.Till
+		}
+
+		out = append(out, piece)
+	}
+
+	return out, nil
+}
+
+// Lookback returns the period the entries with pieces starting within the period may start before it.
+func (s *Split) Lookback() time.Duration {
+	return constants.LOOKBACK
+}
+
+type SplitSpec struct{}
+
+func defaultSplitSpec() SplitSpec {
+	return SplitSpec{}
+}
+
+func createSplit(spec SplitSpec, location *time.Location) (*Split, error) {
+	return &Split{
+		Location: location,
+	}, nil
+}
+
+type TimeEntryTransformConfig struct {
+	Kind string `yaml:"kind" schema:"required"`
+	// Spec is decoded once the kind is known, into the spec of the kind (e.g. RoundSpec)
+	Spec yaml.Node `yaml:"spec,omitempty"`
+}
+
+func NewTimeEntryTransform(config TimeEntryTransformConfig, location *time.Location) (TimeEntryTransform, error) {
+	return create(TimeEntryTransformKinds, "transform", config.Kind, &config.Spec, environment{location: location})
+}
+
+// ResolveTimeEntryTransformConfig returns the config with the defaults of the transform applied to its spec.
+func ResolveTimeEntryTransformConfig(config TimeEntryTransformConfig, location *time.Location) (TimeEntryTransformConfig, error) {
+	spec, err := resolve(TimeEntryTransformKinds, "transform", config.Kind, &config.Spec, environment{location: location})
+	if err != nil {
+		return config, err
+	}
+
+	config.Spec = spec
+
+	return config, nil
+}
+
+// TimeEntryTransforms applies every transform in order, the output of one being the input of the next.
+type TimeEntryTransforms []TimeEntryTransform
+
+func (t TimeEntryTransforms) TransformTimeEntries(entries []TimeEntry) ([]TimeEntry, error) {
+	for _, transform := range t {
+		entries_, err := transform.TransformTimeEntries(entries)
+		if err != nil {
+			return nil, err
+		}
+		entries = entries_
+	}
+
+	return entries, nil
+}
+
+// Lookback returns how much earlier than the period the entries are to be pulled for the transforms, only
+// some transforms need earlier entries (and others, e.g. Round and Merge, would be affected by them).
+func (t TimeEntryTransforms) Lookback() time.Duration {
+	var out time.Duration
+	for _, transform := range t {
+		if transform, ok := transform.(lookback); ok {
+			out = max(out, transform.Lookback())
+		}
+	}
+	return out
+}
+
+func NewTimeEntryTransforms(configs []TimeEntryTransformConfig, location *time.Location) (TimeEntryTransforms, error) {
+	var transforms TimeEntryTransforms
+	for _, config := range configs {
+		transform, err := NewTimeEntryTransform(config, location)
+		if err != nil {
+			return nil, err
+		}
+
+		transforms = append(transforms, transform)
+	}
+
+	return transforms, nil
+}
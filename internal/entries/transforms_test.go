@@ -0,0 +1,164 @@
+package entries
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tornermarton/timesheets/internal/constants"
+)
+
+func durations(entries []TimeEntry) []time.Duration {
+	out := make([]time.Duration, len(entries))
+	for i, entry := range entries {
+		out[i] = entry.Till.Sub(entry.From)
+	}
+	return out
+}
+
+func entriesOf(ds ...time.Duration) []TimeEntry {
+	from := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	entries := make([]TimeEntry, len(ds))
+	for i, d := range ds {
+		entries[i] = TimeEntry{From: from, Till: from.Add(d)}
+		from = from.Add(d)
+	}
+	return entries
+}
+
+func TestRound(t *testing.T) {
+	cases := []struct {
+		round    Round
+		expected []time.Duration
+	}{
+		{Round{Mode: "up", Unit: 15 * time.Minute, Location: time.UTC}, []time.Duration{15 * time.Minute, 15 * time.Minute, 30 * time.Minute}},
+		{Round{Mode: "down", Unit: 15 * time.Minute, Location: time.UTC}, []time.Duration{15 * time.Minute, 15 * time.Minute}},
+		{Round{Mode: "nearest", Unit: 15 * time.Minute, Location: time.UTC}, []time.Duration{15 * time.Minute, 15 * time.Minute, 15 * time.Minute}},
+		{Round{Mode: "down", Unit: 15 * time.Minute, Carry: true, Location: time.UTC}, []time.Duration{15 * time.Minute, 30 * time.Minute}},
+	}
+
+	for _, c := range cases {
+		entries, err := c.round.TransformTimeEntries(entriesOf(8*time.Minute, 15*time.Minute, 22*time.Minute))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if actual := durations(entries); !slices.Equal(actual, c.expected) {
+			t.Errorf("%+v: got %v, want %v", c.round, actual, c.expected)
+		}
+	}
+
+	// The carry of a day is not added to the entries of the next day
+	round := Round{Mode: "down", Unit: 15 * time.Minute, Carry: true, Location: time.UTC}
+	from := time.Date(2025, 6, 1, 23, 0, 0, 0, time.UTC)
+	entries, _ := round.TransformTimeEntries([]TimeEntry{
+		{From: from, Till: from.Add(22 * time.Minute)},
+		{From: from.Add(2 * time.Hour), Till: from.Add(2*time.Hour + 22*time.Minute)},
+	})
+	if actual := durations(entries); !slices.Equal(actual, []time.Duration{15 * time.Minute, 15 * time.Minute}) {
+		t.Errorf("expected the carry to start over on the next day, got %v", actual)
+	}
+}
+
+func TestDrop(t *testing.T) {
+	drop := Drop{Shorter: time.Minute}
+
+	entries, err := drop.TransformTimeEntries(entriesOf(20*time.Second, time.Minute, time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if actual := durations(entries); len(actual) != 2 || actual[0] != time.Minute || actual[1] != time.Hour {
+		t.Errorf("unexpected durations: %v", actual)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	at := func(day int, hour int, minute int) time.Time { return time.Date(2025, 6, day, hour, minute, 0, 0, time.UTC) }
+
+	entries := []TimeEntry{
+		{Id: "1", Issue: "ABC-1", Description: "Review", From: at(1, 9, 0), Till: at(1, 9, 30)},
+		{Id: "2", Issue: "ABC-1", Description: "Fix", From: at(1, 9, 35), Till: at(1, 10, 0)},
+		{Id: "3", Issue: "ABC-2", Description: "Meeting", From: at(1, 10, 0), Till: at(1, 11, 0)},
+		{Id: "4", Issue: "ABC-1", Description: "Review", From: at(1, 13, 0), Till: at(1, 14, 0)},
+		{Id: "5", Issue: "ABC-1", Description: "Review", From: at(2, 9, 0), Till: at(2, 10, 0)},
+	}
+
+	consecutive := Merge{Scope: "consecutive", Gap: 5 * time.Minute, Descriptions: "dedupe", Location: time.UTC}
+	merged, err := consecutive.TransformTimeEntries(entries)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(merged) != 4 || merged[0].Description != "Review; Fix" || merged[0].Till != at(1, 9, 55) {
+		t.Errorf("unexpected consecutive merge: %+v", merged)
+	}
+	if merged[0].Id == merged[2].Id {
+		t.Errorf("expected separate groups of the same issue to be told apart: %+v", merged)
+	}
+
+	// The identity of a group does not depend on its entries, so its worklog is updated instead of booked again
+	shrunk, _ := consecutive.TransformTimeEntries(append(entries[:1:1], entries[2:]...))
+	if len(shrunk) != 4 || shrunk[0].Id != merged[0].Id || shrunk[2].Id != merged[2].Id {
+		t.Errorf("expected the groups to keep their identity, got %+v, want %+v", shrunk, merged)
+	}
+
+	day := Merge{Scope: "day", Descriptions: "match", Location: time.UTC}
+	merged, err = day.TransformTimeEntries(entries)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(merged) != 4 || merged[0].Till != at(1, 10, 30) || merged[0].Id == merged[3].Id {
+		t.Errorf("unexpected day merge: %+v", merged)
+	}
+
+	shrunk, _ = day.TransformTimeEntries(entries[1:])
+	if len(shrunk) != 4 || shrunk[2].Id != merged[0].Id {
+		t.Errorf("expected the day group to keep its identity, got %+v, want %+v", shrunk, merged)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	location, _ := time.LoadLocation("Europe/Budapest")
+	split := Split{Location: location}
+
+	from := time.Date(2025, 6, 1, 22, 30, 0, 0, location)
+	entries, err := split.TransformTimeEntries([]TimeEntry{
+		{Id: "1", From: from, Till: from.Add(2*time.Hour + 45*time.Minute)},
+		{Id: "2", From: from.Add(-time.Hour), Till: from},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+
+	midnight := time.Date(2025, 6, 2, 0, 0, 0, 0, location)
+	if entries[0].Id != "1" || !entries[0].Till.Equal(midnight) {
+		t.Errorf("unexpected first piece: %+v", entries[0])
+	}
+	if entries[1].Id != "1@2025-06-02" || !entries[1].From.Equal(midnight) || entries[1].Till.Sub(entries[1].From) != 75*time.Minute {
+		t.Errorf("unexpected second piece: %+v", entries[1])
+	}
+	if entries[2].Id != "2" {
+		t.Errorf("expected entry within a day to be kept: %+v", entries[2])
+	}
+
+	// The day of the transition to summer time is only 23 hours long
+	dst := time.Date(2025, 3, 30, 0, 0, 0, 0, location)
+	entries, _ = split.TransformTimeEntries([]TimeEntry{{Id: "3", From: dst, Till: dst.Add(24 * time.Hour)}})
+	if len(entries) != 2 || entries[0].Till.Sub(entries[0].From) != 23*time.Hour {
+		t.Errorf("unexpected split over daylight saving time: %+v", entries)
+	}
+}
+
+func TestTransformsLookback(t *testing.T) {
+	if lookback := (TimeEntryTransforms{&Round{}, &Merge{}}).Lookback(); lookback != 0 {
+		t.Errorf("expected no lookback without split, got %s", lookback)
+	}
+	if lookback := (TimeEntryTransforms{&Round{}, &Split{}}).Lookback(); lookback != constants.LOOKBACK {
+		t.Errorf("expected lookback of split, got %s", lookback)
+	}
+}
@@ -0,0 +1,255 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Options struct {
+	Timeout time.Duration
+	Ca      *string
+
+	// Retries is the number of attempts after the first failed one
+	Retries int
+	// Backoff is the delay before the first retry, doubled for every following one
+	Backoff time.Duration
+	// RateLimit is the maximum number of requests per second, zero meaning unlimited
+	RateLimit float64
+}
+
+// Client is shared by every request of a source or target, retrying failed requests and spacing them
+// according to the rate limit of the service.
+type Client struct {
+	Options Options
+
+	client *http.Client
+
+	mutex sync.Mutex
+	next  time.Time
+}
+
+// Requests that reached the service are only retried when repeating them has no additional effect
+var idempotent = map[string]bool{
+	"GET":     true,
+	"HEAD":    true,
+	"OPTIONS": true,
+	"PUT":     true,
+	"DELETE":  true,
+}
+
+const maxBackoff = time.Minute
+
+func New(options Options) (*Client, error) {
+	client := &http.Client{Timeout: options.Timeout}
+
+	if options.Ca != nil {
+		caCert, err := os.ReadFile(*options.Ca)
+		if err != nil {
+			return nil, err
+		}
+
+		caPool := x509.NewCertPool()
+		if ok := caPool.AppendCertsFromPEM(caCert); !ok {
+			return nil, fmt.Errorf("failed to add CA cert to pool")
+		}
+
+		client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs: caPool,
+			},
+		}
+	}
+
+	return &Client{Options: options, client: client}, nil
+}
+
+// Spec holds the connection fields shared by the specs of sources and targets.
+type Spec struct {
+	Timeout time.Duration `yaml:"timeout"`
+	Ca      *string       `yaml:"ca,omitempty"`
+
+	Retries   int     `yaml:"retries"`
+	RateLimit float64 `yaml:"rateLimit"`
+}
+
+func DefaultSpec() Spec {
+	return Spec{
+		Timeout: 10 * time.Second,
+		Retries: 3,
+	}
+}
+
+// NewFromSpec creates the client from the common connection fields of a source or target spec.
+func NewFromSpec(spec Spec, subject string) (*Client, error) {
+	if spec.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid 'timeout' spec for %s: %s", subject, spec.Timeout)
+	}
+
+	if spec.Retries < 0 {
+		return nil, fmt.Errorf("invalid 'retries' spec for %s: %d", subject, spec.Retries)
+	}
+
+	if spec.RateLimit < 0 {
+		return nil, fmt.Errorf("invalid 'rateLimit' spec for %s: %v", subject, spec.RateLimit)
+	}
+
+	client, err := New(Options{
+		Timeout:   spec.Timeout,
+		Ca:        spec.Ca,
+		Retries:   spec.Retries,
+		Backoff:   time.Second,
+		RateLimit: spec.RateLimit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'ca' spec for %s: %w", subject, err)
+	}
+
+	return client, nil
+}
+
+// wait blocks until the rate limit allows the next request.
+func (c *Client) wait(ctx context.Context) error {
+	if c.Options.RateLimit <= 0 {
+		return nil
+	}
+
+	c.mutex.Lock()
+	now := time.Now()
+	slot := c.next
+	if slot.Before(now) {
+		slot = now
+	}
+	c.next = slot.Add(time.Duration(float64(time.Second) / c.Options.RateLimit))
+	c.mutex.Unlock()
+
+	return sleep(ctx, slot.Sub(now))
+}
+
+func sleep(ctx context.Context, duration time.Duration) error {
+	if duration <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryAfter parses the Retry-After header, given either in seconds or as an HTTP date.
+func retryAfter(response *http.Response) (time.Duration, bool) {
+	value := response.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date), true
+	}
+
+	return 0, false
+}
+
+// shouldRetry decides based on the response (nil on network errors) whether the request is worth repeating.
+func shouldRetry(request *http.Request, response *http.Response) bool {
+	if response == nil {
+		return idempotent[request.Method]
+	}
+
+	switch response.StatusCode {
+	case http.StatusTooManyRequests:
+		// Rejected by the rate limiter, so the request was not processed at all
+		return true
+	case http.StatusPaymentRequired:
+		// Toggl responds with 402 once the hourly quota is used up, which is only worth waiting for when told how long
+		_, ok := retryAfter(response)
+		return ok
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent[request.Method]
+	default:
+		return false
+	}
+}
+
+func (c *Client) backoff(attempt int, response *http.Response) time.Duration {
+	if response != nil {
+		if delay, ok := retryAfter(response); ok {
+			return min(delay, maxBackoff)
+		}
+	}
+
+	// Doubling stops at the maximum, shifting by the attempt would overflow with many retries
+	delay := c.Options.Backoff
+	for range attempt {
+		if delay >= maxBackoff {
+			break
+		}
+		delay *= 2
+	}
+	delay = min(delay, maxBackoff)
+
+	// Jitter spreads the retries of concurrent requests
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// Do sends the request, retrying it on failures deemed transient. The request is bound to its context,
+// so cancelling it (e.g. on interrupt) aborts both the request and any pending wait.
+func (c *Client) Do(request *http.Request) (*http.Response, error) {
+	ctx := request.Context()
+
+	// Bodies without GetBody cannot be sent again
+	replayable := request.Body == nil || request.GetBody != nil
+
+	for attempt := 0; ; attempt++ {
+		if err := c.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		if attempt > 0 && request.GetBody != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			request.Body = body
+		}
+
+		response, err := c.client.Do(request)
+		if err != nil && ctx.Err() != nil {
+			return nil, err
+		}
+
+		if attempt >= c.Options.Retries || !replayable || !shouldRetry(request, response) {
+			return response, err
+		}
+
+		delay := c.backoff(attempt, response)
+
+		if response != nil {
+			// The body must be consumed for the connection to be reused
+			io.Copy(io.Discard, response.Body)
+			response.Body.Close()
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoRetriesTooManyRequests(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, _ := New(Options{Timeout: time.Second, Retries: 3, Backoff: time.Millisecond})
+
+	request, _ := http.NewRequest("POST", server.URL, strings.NewReader("{}"))
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK || attempts.Load() != 3 {
+		t.Errorf("expected success after 3 attempts, got %d after %d", response.StatusCode, attempts.Load())
+	}
+}
+
+func TestDoRetriesIdempotentOnly(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, _ := New(Options{Timeout: time.Second, Retries: 2, Backoff: time.Millisecond})
+
+	cases := []struct {
+		method   string
+		attempts int32
+	}{
+		{"GET", 3},
+		{"PUT", 3},
+		// The request might have been processed, so repeating it could create duplicates
+		{"POST", 1},
+	}
+
+	for _, c := range cases {
+		attempts.Store(0)
+
+		request, _ := http.NewRequest(c.method, server.URL, nil)
+		response, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		response.Body.Close()
+
+		if attempts.Load() != c.attempts {
+			t.Errorf("%s: expected %d attempts, got %d", c.method, c.attempts, attempts.Load())
+		}
+	}
+}
+
+func TestDoCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client, _ := New(Options{Timeout: time.Second, Retries: 3, Backoff: time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	request, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+
+	started := time.Now()
+	if _, err := client.Do(request); err == nil {
+		t.Fatalf("expected the request to be cancelled")
+	}
+
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("cancellation did not interrupt the backoff, took %s", elapsed)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	response := &http.Response{Header: http.Header{}}
+
+	response.Header.Set("Retry-After", "7")
+	if delay, ok := retryAfter(response); !ok || delay != 7*time.Second {
+		t.Errorf("unexpected delay for seconds: %s", delay)
+	}
+
+	response.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if delay, ok := retryAfter(response); !ok || delay < 59*time.Minute || delay > time.Hour {
+		t.Errorf("unexpected delay for date: %s", delay)
+	}
+
+	response.Header.Set("Retry-After", "soon")
+	if _, ok := retryAfter(response); ok {
+		t.Errorf("expected invalid value to be ignored")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	client := &Client{Options: Options{Backoff: time.Second}}
+
+	for _, attempt := range []int{0, 3, 63, 64, 1000} {
+		delay := client.backoff(attempt, nil)
+		if delay <= 0 || delay > maxBackoff {
+			t.Errorf("attempt %d: expected a delay within (0, %s], got %s", attempt, maxBackoff, delay)
+		}
+	}
+
+	if delay := client.backoff(1000, nil); delay < maxBackoff/2 {
+		t.Errorf("expected the delay to stay at the maximum, got %s", delay)
+	}
+}
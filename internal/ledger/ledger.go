@@ -0,0 +1,138 @@
+package ledger
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+type Record struct {
+	Target   string `json:"target"`
+	Entry    string `json:"entry"`
+	Worklog  string `json:"worklog"`
+	Checksum string `json:"checksum"`
+
+	Issue       string    `json:"issue"`
+	Description string    `json:"description"`
+	From        time.Time `json:"from"`
+	Till        time.Time `json:"till"`
+}
+
+// Ledger is safe for concurrent use, entries may be synchronized in parallel
+type Ledger struct {
+	path string
+
+	mutex   sync.Mutex
+	records map[string]Record
+}
+
+type ledgerFile struct {
+	Records []Record `json:"records"`
+}
+
+func key(target string, entry string) string {
+	return target + "/" + entry
+}
+
+func compare(a Record, b Record) int {
+	return cmp.Or(a.From.Compare(b.From), cmp.Compare(key(a.Target, a.Entry), key(b.Target, b.Entry)))
+}
+
+func Open(path string) (*Ledger, error) {
+	ledger := &Ledger{
+		path:    path,
+		records: map[string]Record{},
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ledger, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file ledgerFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, err
+	}
+
+	for _, record := range file.Records {
+		ledger.records[key(record.Target, record.Entry)] = record
+	}
+
+	return ledger, nil
+}
+
+func (l *Ledger) Get(target string, entry string) (Record, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	record, ok := l.records[key(target, entry)]
+	return record, ok
+}
+
+func (l *Ledger) Put(record Record) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.records[key(record.Target, record.Entry)] = record
+}
+
+func (l *Ledger) Delete(target string, entry string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.records, key(target, entry))
+}
+
+// Records returns the records of the target starting within [from, till).
+func (l *Ledger) Records(target string, from time.Time, till time.Time) []Record {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var records []Record
+	for _, record := range l.records {
+		if record.Target == target && !record.From.Before(from) && record.From.Before(till) {
+			records = append(records, record)
+		}
+	}
+
+	slices.SortFunc(records, compare)
+
+	return records
+}
+
+func (l *Ledger) Save() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	file := ledgerFile{Records: make([]Record, 0, len(l.records))}
+	for _, record := range l.records {
+		file.Records = append(file.Records, record)
+	}
+
+	slices.SortFunc(file.Records, compare)
+
+	content, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so an interrupted sync never leaves a truncated ledger behind
+	temp := l.path + ".tmp"
+	if err := os.WriteFile(temp, content, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(temp, l.path)
+}
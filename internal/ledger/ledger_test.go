@@ -0,0 +1,72 @@
+package ledger
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpenMissing(t *testing.T) {
+	ledger, err := Open(filepath.Join(t.TempDir(), "ledger.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, ok := ledger.Get("CapsysKronos", "TogglTrack/1"); ok {
+		t.Errorf("expected empty ledger")
+	}
+}
+
+func TestSaveAndOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "ledger.json")
+	from := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	ledger, err := Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ledger.Put(Record{Target: "CapsysKronos", Entry: "TogglTrack/1", Worklog: "42", From: from, Till: from.Add(time.Hour)})
+	if err := ledger.Save(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	record, ok := reopened.Get("CapsysKronos", "TogglTrack/1")
+	if !ok {
+		t.Fatalf("expected record to be persisted")
+	}
+	if record.Worklog != "42" || !record.From.Equal(from) {
+		t.Errorf("unexpected record: %+v", record)
+	}
+
+	if _, ok := reopened.Get("JiraWorklog", "TogglTrack/1"); ok {
+		t.Errorf("expected records to be scoped by target")
+	}
+}
+
+func TestRecords(t *testing.T) {
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	till := from.Add(24 * time.Hour)
+
+	ledger, err := Open(filepath.Join(t.TempDir(), "ledger.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ledger.Put(Record{Target: "CapsysKronos", Entry: "TogglTrack/3", From: from.Add(10 * time.Hour)})
+	ledger.Put(Record{Target: "CapsysKronos", Entry: "TogglTrack/2", From: from})
+	ledger.Put(Record{Target: "CapsysKronos", Entry: "TogglTrack/4", From: till})
+	ledger.Put(Record{Target: "JiraWorklog", Entry: "TogglTrack/2", From: from})
+	ledger.Put(Record{Target: "CapsysKronos", Entry: "TogglTrack/5", From: from.Add(12 * time.Hour)})
+	ledger.Delete("CapsysKronos", "TogglTrack/5")
+
+	records := ledger.Records("CapsysKronos", from, till)
+	if len(records) != 2 || records[0].Entry != "TogglTrack/2" || records[1].Entry != "TogglTrack/3" {
+		t.Errorf("unexpected records: %+v", records)
+	}
+}
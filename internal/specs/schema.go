@@ -0,0 +1,109 @@
+package specs
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// durationPattern matches the durations accepted by time.ParseDuration, e.g. 1h30m or 500ms
+const durationPattern = `^-?([0-9]+(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+$|^0$`
+
+// Schema returns the JSON Schema of the type of the value, with the values of its fields as their defaults.
+//
+// Fields can be further described by the schema tag, e.g. `schema:"required,enum=up|down"` marks a field
+// required and lists its allowed values (or the allowed values of its items for lists).
+func Schema(value any) map[string]any {
+	return schemaOf(reflect.ValueOf(value))
+}
+
+func schemaOf(value reflect.Value) map[string]any {
+	t := value.Type()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		if value.IsNil() {
+			value = reflect.Zero(t)
+		} else {
+			value = value.Elem()
+		}
+	}
+
+	switch t {
+	case nodeType:
+		return map[string]any{}
+	case durationType:
+		return map[string]any{"type": "string", "pattern": durationPattern}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaOf(reflect.Zero(t.Elem()))}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(reflect.Zero(t.Elem()))}
+	case reflect.Struct:
+		properties := map[string]any{}
+		required := []string{}
+
+		for name, field := range fields(t) {
+			fieldValue := value.FieldByIndex(field.Index)
+			property := schemaOf(fieldValue)
+
+			// Nested structs carry the defaults of their own fields
+			if !fieldValue.IsZero() && fieldValue.Kind() != reflect.Struct {
+				property["default"] = defaultOf(fieldValue)
+			}
+
+			for _, option := range strings.Split(field.Tag.Get("schema"), ",") {
+				switch {
+				case option == "required":
+					required = append(required, name)
+				case strings.HasPrefix(option, "enum="):
+					values := strings.Split(strings.TrimPrefix(option, "enum="), "|")
+					if items, ok := property["items"].(map[string]any); ok {
+						items["enum"] = values
+					} else {
+						property["enum"] = values
+					}
+				}
+			}
+
+			properties[name] = property
+		}
+
+		schema := map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+		if rest, ok := remainder(t); ok {
+			schema["additionalProperties"] = schemaOf(reflect.Zero(rest.Type.Elem()))
+		}
+		if len(required) > 0 {
+			slices.Sort(required)
+			schema["required"] = required
+		}
+		return schema
+	default:
+		return map[string]any{}
+	}
+}
+
+func defaultOf(value reflect.Value) any {
+	for value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+
+	if value.Type() == durationType {
+		return value.Interface().(time.Duration).String()
+	}
+	return value.Interface()
+}
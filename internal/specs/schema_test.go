@@ -0,0 +1,57 @@
+package specs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testSchemaSpec struct {
+	Token string        `yaml:"token" schema:"required"`
+	Mode  string        `yaml:"mode" schema:"enum=up|down"`
+	Issue []string      `yaml:"issue" schema:"enum=description|project"`
+	Gap   time.Duration `yaml:"gap"`
+
+	testConnection `yaml:",inline"`
+
+	Defaults testDefaults `yaml:"defaults"`
+}
+
+func TestSchema(t *testing.T) {
+	schema := Schema(testSchemaSpec{
+		Mode:           "up",
+		Issue:          []string{"description"},
+		testConnection: testConnection{Timeout: 10 * time.Second},
+		Defaults:       testDefaults{SiteId: 31},
+	})
+
+	if !reflect.DeepEqual(schema["required"], []string{"token"}) || schema["additionalProperties"] != false {
+		t.Errorf("expected a closed object requiring the token, got %v", schema)
+	}
+
+	properties := schema["properties"].(map[string]any)
+	expected := map[string]any{
+		"token":   map[string]any{"type": "string"},
+		"mode":    map[string]any{"type": "string", "enum": []string{"up", "down"}, "default": "up"},
+		"issue":   map[string]any{"type": "array", "items": map[string]any{"type": "string", "enum": []string{"description", "project"}}, "default": []string{"description"}},
+		"gap":     map[string]any{"type": "string", "pattern": durationPattern},
+		"timeout": map[string]any{"type": "string", "pattern": durationPattern, "default": "10s"},
+	}
+	for name, property := range expected {
+		if !reflect.DeepEqual(properties[name], property) {
+			t.Errorf("%s: got %v, want %v", name, properties[name], property)
+		}
+	}
+
+	defaults := properties["defaults"].(map[string]any)["properties"].(map[string]any)
+	if defaults["siteId"].(map[string]any)["default"] != 31 {
+		t.Errorf("expected the defaults of nested fields, got %v", defaults)
+	}
+}
+
+func TestSchemaRemainder(t *testing.T) {
+	schema := Schema(testFields{})
+	if _, ok := schema["properties"].(map[string]any)["siteId"]; !ok || !reflect.DeepEqual(schema["additionalProperties"], map[string]any{}) {
+		t.Errorf("expected an object open to other properties, got %v", schema)
+	}
+}
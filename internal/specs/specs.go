@@ -0,0 +1,198 @@
+package specs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var nodeType = reflect.TypeFor[yaml.Node]()
+
+// fields returns the fields of the struct by their YAML name, including the fields of inlined structs (with
+// their index relative to the struct).
+func fields(t reflect.Type) map[string]reflect.StructField {
+	out := map[string]reflect.StructField{}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if slices.Contains(strings.Split(options, ","), "inline") {
+			// An inlined map collects the keys without a field, see remainder
+			if field.Type.Kind() == reflect.Map {
+				continue
+			}
+			for name, inner := range fields(field.Type) {
+				inner.Index = append([]int{i}, inner.Index...)
+				out[name] = inner
+			}
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		out[name] = field
+	}
+	return out
+}
+
+// remainder returns the inlined map of the struct (if any), which collects the keys without a field.
+func remainder(t reflect.Type) (reflect.StructField, bool) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		_, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if field.Type.Kind() == reflect.Map && slices.Contains(strings.Split(options, ","), "inline") {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// pairs returns the key and value pairs of the mapping, with the mappings merged by merge keys (<<: *anchor)
+// expanded in place of the merge keys.
+func pairs(node *yaml.Node) [][2]*yaml.Node {
+	var out [][2]*yaml.Node
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Tag != "!!merge" {
+			out = append(out, [2]*yaml.Node{key, value})
+			continue
+		}
+
+		merged := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			merged = value.Content
+		}
+		for _, mapping := range merged {
+			for mapping.Kind == yaml.AliasNode {
+				mapping = mapping.Alias
+			}
+			if mapping.Kind == yaml.MappingNode {
+				out = append(out, pairs(mapping)...)
+			}
+		}
+	}
+	return out
+}
+
+// unknownFields reports the keys of the mappings that have no corresponding field in the type.
+func unknownFields(node *yaml.Node, t reflect.Type) []error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// Nested nodes (e.g. the spec of a source) are decoded (and checked) on their own
+	if t == nodeType {
+		return nil
+	}
+
+	if node.Kind == yaml.DocumentNode {
+		return unknownFields(node.Content[0], t)
+	}
+	if node.Kind == yaml.AliasNode {
+		return unknownFields(node.Alias, t)
+	}
+
+	var errs []error
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := fields(t)
+		rest, passed := remainder(t)
+		for _, pair := range pairs(node) {
+			key, value := pair[0], pair[1]
+
+			field, ok := fields[key.Value]
+			if !ok {
+				var suggestion string
+				for name := range fields {
+					if strings.EqualFold(name, key.Value) {
+						suggestion = name
+					}
+				}
+
+				// Keys passed through are still checked for typos of the fields (e.g. activityTypeID)
+				if passed && suggestion == "" {
+					errs = append(errs, unknownFields(value, rest.Type.Elem())...)
+					continue
+				}
+
+				message := fmt.Sprintf("unknown field %q", key.Value)
+				if suggestion != "" {
+					message += fmt.Sprintf(" (did you mean %q?)", suggestion)
+				}
+				errs = append(errs, fmt.Errorf("line %d: %s", key.Line, message))
+				continue
+			}
+
+			errs = append(errs, unknownFields(value, field.Type)...)
+		}
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for _, pair := range pairs(node) {
+			errs = append(errs, unknownFields(pair[1], t.Elem())...)
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for _, item := range node.Content {
+			errs = append(errs, unknownFields(item, t.Elem())...)
+		}
+	}
+
+	return errs
+}
+
+// line returns the line the error occurs on, zero if unknown.
+func line(err error) int {
+	var line int
+	fmt.Sscanf(err.Error(), "line %d:", &line)
+	return line
+}
+
+// Sort flattens the (joined) errors and orders them by the line they occur on, errors without a line first.
+func Sort(errs []error) []error {
+	var out []error
+	for _, err := range errs {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			out = append(out, Sort(joined.Unwrap())...)
+		} else {
+			out = append(out, err)
+		}
+	}
+
+	slices.SortStableFunc(out, func(a error, b error) int { return line(a) - line(b) })
+
+	return out
+}
+
+// Decode decodes the node into out strictly, reporting every unknown field and every value of the wrong
+// type (instead of only the first one), each prefixed by the line it occurs on.
+func Decode(node *yaml.Node, out any) error {
+	// A missing node (e.g. an omitted spec) leaves the defaults untouched
+	if node.Kind == 0 {
+		return nil
+	}
+
+	errs := unknownFields(node, reflect.TypeOf(out))
+
+	if err := node.Decode(out); err != nil {
+		var typeError *yaml.TypeError
+		if !errors.As(err, &typeError) {
+			return err
+		}
+
+		for _, message := range typeError.Errors {
+			errs = append(errs, errors.New(message))
+		}
+	}
+
+	return errors.Join(Sort(errs)...)
+}
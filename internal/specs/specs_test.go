@@ -0,0 +1,133 @@
+package specs
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type testDefaults struct {
+	SiteId  int    `yaml:"siteId"`
+	Comment string `yaml:"comment"`
+}
+type testConnection struct {
+	Timeout time.Duration `yaml:"timeout"`
+}
+type testSpec struct {
+	Workspace int `yaml:"workspace"`
+
+	testConnection `yaml:",inline"`
+
+	Tags     map[string]testDefaults `yaml:"tags"`
+	Defaults testDefaults            `yaml:"defaults"`
+	Nested   yaml.Node               `yaml:"nested"`
+}
+
+func parse(t *testing.T, content string) *yaml.Node {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &node); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return &node
+}
+
+func TestDecode(t *testing.T) {
+	spec := testSpec{Defaults: testDefaults{SiteId: 31, Comment: "default"}}
+
+	err := Decode(parse(t, "workspace: 1\ntimeout: 5s\ndefaults:\n  comment: custom\nnested:\n  anything: goes\n"), &spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if spec.Workspace != 1 || spec.Timeout != 5*time.Second || spec.Defaults != (testDefaults{SiteId: 31, Comment: "custom"}) {
+		t.Errorf("expected the values to be decoded on top of the defaults, got %+v", spec)
+	}
+}
+
+func TestDecodeReportsEveryProblem(t *testing.T) {
+	content := `workspace: "abc"
+timeouts: 5s
+tags:
+  development: { siteID: 5 }
+defaults:
+  siteId: many
+`
+
+	err := Decode(parse(t, content), &testSpec{})
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	problems := strings.Split(err.Error(), "\n")
+	expected := []string{
+		"line 1: cannot unmarshal !!str `abc` into int",
+		`line 2: unknown field "timeouts"`,
+		`line 4: unknown field "siteID" (did you mean "siteId"?)`,
+		"line 6: cannot unmarshal !!str `many` into int",
+	}
+	if strings.Join(problems, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", err, strings.Join(expected, "\n"))
+	}
+}
+
+type testFields struct {
+	SiteId *int           `yaml:"siteId,omitempty"`
+	Other  map[string]any `yaml:",inline"`
+}
+
+func TestDecodeRemainder(t *testing.T) {
+	var fields testFields
+	if err := Decode(parse(t, "siteId: 5\nissueKey: ABC-1\n"), &fields); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if *fields.SiteId != 5 || len(fields.Other) != 1 || fields.Other["issueKey"] != "ABC-1" {
+		t.Errorf("expected the keys without a field to be kept, got %+v", fields)
+	}
+
+	err := Decode(parse(t, "siteID: 5\n"), &testFields{})
+	if err == nil || err.Error() != `line 1: unknown field "siteID" (did you mean "siteId"?)` {
+		t.Errorf("expected a typo of a field to be reported, got %v", err)
+	}
+}
+
+func TestDecodeMissing(t *testing.T) {
+	spec := testSpec{Workspace: 1}
+	if err := Decode(&yaml.Node{}, &spec); err != nil || spec.Workspace != 1 {
+		t.Errorf("expected a missing node to keep the defaults, got %+v (%v)", spec, err)
+	}
+}
+
+func TestDecodeMergeKeys(t *testing.T) {
+	content := `base: &base
+  siteId: 5
+  comment: shared
+other: &other
+  siteID: 6
+spec:
+  workspace: 1
+  defaults:
+    <<: *base
+    comment: custom
+  tags:
+    development:
+      <<: [*base, *other]
+`
+
+	var root struct {
+		Spec yaml.Node `yaml:"spec"`
+	}
+	if err := parse(t, content).Decode(&root); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var spec testSpec
+	err := Decode(&root.Spec, &spec)
+	if err == nil || err.Error() != `line 5: unknown field "siteID" (did you mean "siteId"?)` {
+		t.Errorf("expected only the unknown field of the merged mapping, got %v", err)
+	}
+	if spec.Defaults != (testDefaults{SiteId: 5, Comment: "custom"}) {
+		t.Errorf("expected the merged values to be decoded, got %+v", spec.Defaults)
+	}
+}
@@ -1,14 +1,8 @@
 package utils
 
 import (
-	"crypto/tls"
-	"crypto/x509"
 	"errors"
-	"fmt"
-	"net/http"
-	"os"
 	"strings"
-	"time"
 )
 
 func GetErrorMessage(err error) string {
@@ -87,28 +81,3 @@ func Coalesce[T any](v *T, fallback T) T {
 	}
 	return *v
 }
-
-func CreateHttpClient(timeout time.Duration, caPath *string) (*http.Client, error) {
-	if caPath == nil {
-		return &http.Client{Timeout: timeout}, nil
-	}
-
-	caCert, err := os.ReadFile(*caPath)
-	if err != nil {
-		return nil, err
-	}
-
-	caPool := x509.NewCertPool()
-	if ok := caPool.AppendCertsFromPEM(caCert); !ok {
-		return nil, fmt.Errorf("failed to add CA cert to pool")
-	}
-
-	return &http.Client{
-		Timeout: timeout,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				RootCAs: caPool,
-			},
-		},
-	}, nil
-}
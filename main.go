@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-
 	"log"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
 
 	"github.com/tornermarton/timesheets/cmd"
 	"github.com/tornermarton/timesheets/internal/cli"
 	cfg "github.com/tornermarton/timesheets/internal/config"
+	"github.com/tornermarton/timesheets/internal/constants"
 )
 
 // Values are set during the build process using -ldflags.
@@ -17,8 +22,21 @@ var (
 	version string = ""
 )
 
+// isCommand returns whether the arguments invoke the (sub)command, skipping the options before each name.
+func isCommand(args []string) func(names []string) bool {
+	return func(names []string) bool {
+		var positional []string
+		for _, arg := range args {
+			if !strings.HasPrefix(arg, "-") {
+				positional = append(positional, arg)
+			}
+		}
+		return len(positional) >= len(names) && slices.Equal(positional[:len(names)], names)
+	}
+}
+
 func main() {
-	command := &cli.FlagSet{FlagSet: flag.NewFlagSet("timesheets", flag.ExitOnError)}
+	command := &cli.FlagSet{FlagSet: flag.NewFlagSet("timesheets", flag.ContinueOnError)}
 
 	configFlag := command.String("config", cfg.GetDefaultPath(), "config path")
 
@@ -29,7 +47,9 @@ Synchronize your work logs between multiple timesheet managing systems with ease
 
 Commands:
 
-  config    Print the used configuration.
+  config    Create, print or validate the configuration.
+  diff      Compare the work logs of the source and the target.
+  report    Report the totals of the work logs per day, issue and tag.
   sync      Synchronize your work logs.
   version   Print version information about the timesheets CLI.
 
@@ -48,25 +68,39 @@ For more information, visit: https://github.com/tornermarton/timesheets
 
 	command.Parse(os.Args[1:])
 
-	config, err := cfg.Read(*configFlag)
-	if err != nil {
-		log.Fatal(err)
+	// config init creates the configuration and config schema describes it, so neither reads it (which may
+	// fail, or run token commands, for nothing)
+	config := &cfg.Config{}
+	if !slices.ContainsFunc([][]string{{"config", "init"}, {"config", "schema"}}, isCommand(command.Args())) {
+		var err error
+		if config, err = cfg.Read(*configFlag); err != nil {
+			log.Fatal(err)
+		}
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	context := &cli.Context{
-		Version: version,
-		Config:  config,
+		Version:    version,
+		Config:     config,
+		ConfigPath: *configFlag,
+		Ctx:        ctx,
 	}
 
 	switch command.Arg(0) {
 	case "config":
 		cmd.Config(command.Args()[1:], context)
+	case "diff":
+		cmd.Diff(command.Args()[1:], context)
+	case "report":
+		cmd.Report(command.Args()[1:], context)
 	case "sync":
 		cmd.Sync(command.Args()[1:], context)
 	case "version":
 		cmd.Version(command.Args()[1:], context)
 	default:
 		command.Usage()
-		os.Exit(1)
+		os.Exit(constants.EXIT_ERROR)
 	}
 }